@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"log"
+	"strconv"
+)
+
+// mustConnectDB and mustConnectRedis open the same connections as the http
+// server for the operator commands.
+func mustConnectDB() *sql.DB {
+	db, err := utils.PostConnection()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return db
+}
+
+// mustConnectRedis returns nil when Redis is disabled. Commands only use it to
+// drop cached users, so they carry on without it when it is unreachable. A
+// server caching in memory cannot be reached from here; its cached users
+// expire after CACHE_USER_TTL.
+func mustConnectRedis() *utils.RedisClient {
+	if !config.AppConfig.Redis.Enabled {
+		return nil
+	}
+
+	client, err := utils.RedisConnection(config.AppConfig.Redis.Addr, config.AppConfig.Redis.PW, config.AppConfig.Redis.DB)
+	if err != nil {
+		log.Printf("%v, skipping the cache", err)
+	}
+	return client
+}
+
+func parseUserId(arg string) int64 {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id < 1 {
+		log.Fatal(fmt.Errorf("invalid user id %q", arg))
+	}
+	return id
+}
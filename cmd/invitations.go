@@ -0,0 +1,44 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// invitationsCmd represents the invitations command
+var invitationsCmd = &cobra.Command{
+	Use:   "invitations",
+	Short: "Managing user invitations",
+}
+
+// invitationsPurgeCmd represents the invitations purge command
+var invitationsPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Deleting expired invitations",
+	Run: func(cmd *cobra.Command, args []string) {
+		db := mustConnectDB()
+		defer db.Close()
+
+		userService := service.NewUserService(repository.NewUserRepository(db, db), db)
+
+		purged, err := userService.PurgeInvitations(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("purged %d expired invitations\n", purged)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(invitationsCmd)
+	invitationsCmd.AddCommand(invitationsPurgeCmd)
+}
@@ -27,9 +27,16 @@ var seedCmd = &cobra.Command{
 		postRepository := repository.NewPostRepository(db, db)
 		userRepository := repository.NewUserRepository(db, db)
 		commentRepository := repository.NewCommentRepository(db, db)
-		postService := service.NewPostService(postRepository, db)
+		followerRepository := repository.NewFollowerRepository(db, db)
+		// Seeding runs without Redis, so nothing is cached or invalidated and
+		// timelines are left to the backfill command.
+		cacheRepository := repository.NewCacheRepository(nil)
+		timelineService := service.NewTimelineService(repository.NewTimelineRepository(nil, 0, 0), postRepository, followerRepository, 0, 0)
+		// Seeding writes far more than any account may, so no velocity limits.
+		velocityService := service.NewVelocityService(nil, userRepository, service.VelocityPolicy{})
+		postService := service.NewPostService(postRepository, followerRepository, commentRepository, velocityService, timelineService, cacheRepository, db)
 		userService := service.NewUserService(userRepository, db)
-		commentService := service.NewCommentService(commentRepository)
+		commentService := service.NewCommentService(commentRepository, velocityService, cacheRepository)
 		seed := service.NewSeederService(userService, postService, commentService)
 
 		if err := seed.Seed(context.Background(), db); err != nil {
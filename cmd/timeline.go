@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// timelineBackfillBatch is how many users are read per page while backfilling.
+const timelineBackfillBatch = 500
+
+// timelineCmd represents the timeline command
+var timelineCmd = &cobra.Command{
+	Use:   "timeline",
+	Short: "Managing home timelines",
+}
+
+// timelineBackfillCmd represents the timeline backfill command
+var timelineBackfillCmd = &cobra.Command{
+	Use:   "backfill [user id]",
+	Short: "Rebuilding the home timeline of one user, or of every active user",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := mustConnectDB()
+		defer db.Close()
+		client := mustConnectRedis()
+		defer client.Close()
+
+		if !client.Healthy() {
+			log.Fatal("timelines are kept in redis, which is disabled or unreachable")
+		}
+
+		postRepository := repository.NewPostRepository(db, db)
+		followerRepository := repository.NewFollowerRepository(db, db)
+		timelineService := service.NewTimelineService(
+			repository.NewTimelineRepository(client, config.AppConfig.Timeline.MaxLength, config.AppConfig.Timeline.TTL),
+			postRepository,
+			followerRepository,
+			config.AppConfig.Timeline.MaxLength,
+			config.AppConfig.Timeline.FanOutLimit,
+		)
+
+		ctx := context.Background()
+		if len(args) == 1 {
+			id := parseUserId(args[0])
+			if err := timelineService.Rebuild(ctx, id); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("rebuilt the timeline of user %d\n", id)
+			return
+		}
+
+		userRepository := repository.NewUserRepository(db, db)
+
+		var rebuilt int
+		var afterId int64
+		for {
+			ids, err := userRepository.ListIds(ctx, afterId, timelineBackfillBatch)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(ids) == 0 {
+				break
+			}
+
+			for _, id := range ids {
+				if err = timelineService.Rebuild(ctx, id); err != nil {
+					log.Fatal(err)
+				}
+			}
+			// Writes to an unreachable Redis are skipped, not reported.
+			if !client.Healthy() {
+				log.Fatalf("lost redis after rebuilding %d timelines", rebuilt)
+			}
+			rebuilt += len(ids)
+			afterId = ids[len(ids)-1]
+		}
+
+		fmt.Printf("rebuilt %d timelines\n", rebuilt)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(timelineCmd)
+	timelineCmd.AddCommand(timelineBackfillCmd)
+}
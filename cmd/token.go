@@ -0,0 +1,72 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// tokenCmd represents the token command
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Managing access tokens",
+}
+
+// tokenMintCmd represents the token mint command
+var tokenMintCmd = &cobra.Command{
+	Use:   "mint",
+	Short: "Minting an access token for a user, for debugging",
+	Run: func(cmd *cobra.Command, args []string) {
+		userId, _ := cmd.Flags().GetInt64("user")
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		if ttl == 0 {
+			ttl = config.AppConfig.Mail.Exp
+		}
+
+		db := mustConnectDB()
+		defer db.Close()
+
+		ctx := context.Background()
+		userService := service.NewUserService(repository.NewUserRepository(db, db), db)
+		sessionService := service.NewSessionService(repository.NewSessionRepository(db, db))
+		authenticator := service.NewJWTAuthenticator(config.AppConfig.Authentication.Secret, config.AppConfig.Authentication.Aud, config.AppConfig.Authentication.Iss)
+
+		user, err := userService.GetById(ctx, userId)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		session := &service_models.Session{
+			UserID:    user.ID,
+			UserAgent: "gophergram cli",
+		}
+		if err = sessionService.Create(ctx, session); err != nil {
+			log.Fatal(err)
+		}
+
+		token, err := authenticator.GenerateToken(service.SessionClaims(user.ID, session.ID, ttl))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(token)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenMintCmd)
+
+	tokenMintCmd.Flags().Int64("user", 0, "id of the user the token is issued for")
+	tokenMintCmd.Flags().Duration("ttl", 0, "lifetime of the token, defaults to the login token lifetime")
+	_ = tokenMintCmd.MarkFlagRequired("user")
+}
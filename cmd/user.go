@@ -0,0 +1,226 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// userCmd represents the user command
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Managing user accounts",
+}
+
+// userCreateCmd represents the user create command
+var userCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creating an activated user without sending an invitation",
+	Run: func(cmd *cobra.Command, args []string) {
+		username, _ := cmd.Flags().GetString("username")
+		email, _ := cmd.Flags().GetString("email")
+		password, _ := cmd.Flags().GetString("password")
+		role, _ := cmd.Flags().GetString("role")
+
+		generated := password == ""
+		if generated {
+			password = randomPassword()
+		}
+
+		payload := service_models.RegisterUserPayload{
+			Username: username,
+			Email:    email,
+			Password: password,
+		}
+		if err := helper.Validate.Struct(payload); err != nil {
+			log.Fatal(err)
+		}
+
+		db := mustConnectDB()
+		defer db.Close()
+
+		ctx := context.Background()
+		userRepository := repository.NewUserRepository(db, db)
+		roleRepository := repository.NewRoleRepository(db, db)
+		userService := service.NewUserService(userRepository, db)
+
+		if _, err := roleRepository.GetByName(ctx, role); err != nil {
+			log.Fatal(fmt.Errorf("unknown role %q: %w", role, err))
+		}
+
+		user := &service_models.User{
+			Username: payload.Username,
+			Email:    payload.Email,
+			Role: service_models.Role{
+				Name: role,
+			},
+		}
+		if err := user.Password.Set(payload.Password); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := userService.CreateActivated(ctx, user); err != nil {
+			log.Fatal(err)
+		}
+
+		recordAudit(db, "user.create", "user", user.ID, nil, map[string]any{"role": role})
+
+		fmt.Printf("created user %d (%s) with role %s\n", user.ID, user.Email, role)
+		if generated {
+			fmt.Printf("generated password: %s\n", password)
+		}
+	},
+}
+
+// userActivateCmd represents the user activate command
+var userActivateCmd = &cobra.Command{
+	Use:   "activate [user id]",
+	Short: "Activating a user without the invitation email",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := parseUserId(args[0])
+
+		db := mustConnectDB()
+		defer db.Close()
+
+		userService := service.NewUserService(repository.NewUserRepository(db, db), db)
+		if err := userService.ActivateById(context.Background(), id); err != nil {
+			log.Fatal(err)
+		}
+		recordAudit(db, "user.activate", "user", id, nil, nil)
+
+		fmt.Printf("activated user %d\n", id)
+	},
+}
+
+// userSuspendCmd represents the user suspend command
+var userSuspendCmd = &cobra.Command{
+	Use:   "suspend [user id]",
+	Short: "Suspending or banning a user, or lifting their suspension",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := parseUserId(args[0])
+		reason, _ := cmd.Flags().GetString("reason")
+		duration, _ := cmd.Flags().GetDuration("duration")
+		lift, _ := cmd.Flags().GetBool("lift")
+
+		db := mustConnectDB()
+		defer db.Close()
+		client := mustConnectRedis()
+		defer client.Close()
+
+		suspensionService := service.NewSuspensionService(
+			repository.NewSuspensionRepository(db, db),
+			repository.NewUserRepository(db, db),
+			repository.NewCacheRepository(client),
+		)
+
+		ctx := context.Background()
+		if lift {
+			if err := suspensionService.Lift(ctx, id, nil); err != nil {
+				log.Fatal(err)
+			}
+			recordAudit(db, "user.unsuspend", "user", id, nil, nil)
+			fmt.Printf("lifted the suspension of user %d\n", id)
+			return
+		}
+
+		if reason == "" {
+			log.Fatal("--reason is required")
+		}
+
+		suspension, err := suspensionService.Suspend(ctx, id, reason, duration, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		recordAudit(db, "user.suspend", "user", id, nil, suspension)
+
+		if suspension.ExpiresAt == nil {
+			fmt.Printf("banned user %d\n", id)
+			return
+		}
+		fmt.Printf("suspended user %d until %s\n", id, suspension.ExpiresAt.Format(time.RFC3339))
+	},
+}
+
+// userSetRoleCmd represents the user set-role command
+var userSetRoleCmd = &cobra.Command{
+	Use:   "set-role [user id] [role]",
+	Short: "Assigning a role to a user",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := parseUserId(args[0])
+
+		db := mustConnectDB()
+		defer db.Close()
+		client := mustConnectRedis()
+		defer client.Close()
+
+		userRepository := repository.NewUserRepository(db, db)
+		roleService := service.NewRoleService(repository.NewRoleRepository(db, db), userRepository, repository.NewCacheRepository(client), db)
+
+		assignment, err := roleService.AssignToUser(context.Background(), id, args[1], nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		recordAudit(db, "user.role.change", "user", id, map[string]any{"role_id": assignment.OldRoleID}, map[string]any{"role_id": assignment.NewRoleID})
+
+		fmt.Printf("user %d now has role %s\n", id, args[1])
+	},
+}
+
+// cliAuditMarker stands in for the request id of audit entries written by
+// operator commands, which have no actor.
+const cliAuditMarker = "cli"
+
+// recordAudit stores an audit entry for a command that already succeeded, so
+// a failure is only reported.
+func recordAudit(db *sql.DB, action, targetType string, targetId int64, before, after any) {
+	auditService := service.NewAuditService(repository.NewAuditRepository(db, db))
+	entry := &service_models.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   &targetId,
+		RequestID:  cliAuditMarker,
+	}
+	if err := auditService.Record(context.Background(), entry, before, after); err != nil {
+		log.Printf("error recording audit log for %s: %v", action, err)
+	}
+}
+
+func randomPassword() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userCreateCmd, userActivateCmd, userSuspendCmd, userSetRoleCmd)
+
+	userCreateCmd.Flags().String("username", "", "username of the new user")
+	userCreateCmd.Flags().String("email", "", "email of the new user")
+	userCreateCmd.Flags().String("password", "", "password of the new user, generated when empty")
+	userCreateCmd.Flags().String("role", service.DefaultRole, "role of the new user")
+	_ = userCreateCmd.MarkFlagRequired("username")
+	_ = userCreateCmd.MarkFlagRequired("email")
+
+	userSuspendCmd.Flags().String("reason", "", "reason shown to moderators")
+	userSuspendCmd.Flags().Duration("duration", 0, "how long the suspension lasts, 0 bans the user")
+	userSuspendCmd.Flags().Bool("lift", false, "lift the active suspension instead")
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/caarlos0/env"
 	"github.com/joho/godotenv"
 	"log"
+	"os"
 	"time"
 )
 
@@ -18,6 +19,13 @@ type Config struct {
 	Authentication Authentication
 	Redis          Redis
 	Rate           Rate
+	Retention      Retention
+	Moderation     Moderation
+	ContentPolicy  ContentPolicy
+	Velocity       Velocity
+	Stats          Stats
+	Cache          Cache
+	Timeline       Timeline
 }
 
 type ServerConfig struct {
@@ -28,6 +36,9 @@ type ServerConfig struct {
 	WriteTimeout time.Duration `env:"SERVER_WRITE_TIMEOUT,required"`
 	Env          string        `env:"SERVER_ENV,required"`
 	APIURL       string        `env:"SERVER_API_URL,required"`
+	// TrustedProxies lists the CIDRs or addresses of the proxies in front of
+	// the server. Forwarding headers are only believed when they come from one.
+	TrustedProxies []string `env:"SERVER_TRUSTED_PROXIES" envSeparator:","`
 }
 
 type Context struct {
@@ -41,18 +52,106 @@ type Authentication struct {
 	Exp      time.Duration `env:"EXP,required"`
 	Aud      string        `env:"AUD,required"`
 	Iss      string        `env:"ISS,required"`
+	// LegacyTokensUntil is an RFC 3339 time until which JWTs issued before
+	// sessions existed, which carry no session id and so cannot be revoked,
+	// are still accepted. Left empty, they are rejected.
+	LegacyTokensUntil  string `env:"AUTH_LEGACY_TOKENS_UNTIL"`
+	LegacyTokensCutoff time.Time
 }
 
+const (
+	RateAlgorithmFixedWindow   = "fixed_window"
+	RateAlgorithmSlidingWindow = "sliding_window"
+	RateAlgorithmGCRA          = "gcra"
+)
+
+// Rate holds the default request limit and the stricter ones for the login
+// and registration endpoints (Auth) and for minting access tokens (Token).
+// IPLimit caps the requests one address may make to authenticated routes
+// before their token is checked, whoever they claim to be.
 type Rate struct {
-	Limit  int           `env:"RATE_LIMIT,required"`
-	Window time.Duration `env:"RATE_WINDOW,required"`
+	Limit       int           `env:"RATE_LIMIT,required"`
+	Window      time.Duration `env:"RATE_WINDOW,required"`
+	IPLimit     int           `env:"RATE_IP_LIMIT" envDefault:"600"`
+	IPWindow    time.Duration `env:"RATE_IP_WINDOW" envDefault:"1m"`
+	Algorithm   string        `env:"RATE_ALGORITHM" envDefault:"sliding_window"`
+	AuthLimit   int           `env:"RATE_AUTH_LIMIT" envDefault:"10"`
+	AuthWindow  time.Duration `env:"RATE_AUTH_WINDOW" envDefault:"1m"`
+	TokenLimit  int           `env:"RATE_TOKEN_LIMIT" envDefault:"10"`
+	TokenWindow time.Duration `env:"RATE_TOKEN_WINDOW" envDefault:"1h"`
+}
+
+type Retention struct {
+	Period   time.Duration `env:"RETENTION_PERIOD" envDefault:"720h"`
+	Interval time.Duration `env:"RETENTION_INTERVAL" envDefault:"1h"`
+}
+
+type Moderation struct {
+	ReportSuspension time.Duration `env:"MODERATION_REPORT_SUSPENSION" envDefault:"168h"`
+}
+
+type ContentPolicy struct {
+	BannedWords       []string      `env:"CONTENT_BANNED_WORDS" envSeparator:","`
+	BannedWordsAction string        `env:"CONTENT_BANNED_WORDS_ACTION" envDefault:"reject"`
+	MaxPostLinks      int           `env:"CONTENT_MAX_POST_LINKS" envDefault:"5"`
+	MaxCommentLinks   int           `env:"CONTENT_MAX_COMMENT_LINKS" envDefault:"2"`
+	RepeatWindow      time.Duration `env:"CONTENT_REPEAT_WINDOW" envDefault:"24h"`
+}
+
+// Velocity caps how often a single account may follow, post and comment.
+// Accounts younger than NewAccountAge get the NewAccount limits. A limit of 0
+// turns the check off.
+type Velocity struct {
+	NewAccountAge               time.Duration `env:"VELOCITY_NEW_ACCOUNT_AGE" envDefault:"168h"`
+	FollowsPerHour              int           `env:"VELOCITY_FOLLOWS_PER_HOUR" envDefault:"100"`
+	NewAccountFollowsPerHour    int           `env:"VELOCITY_NEW_ACCOUNT_FOLLOWS_PER_HOUR" envDefault:"20"`
+	PostsPerDay                 int           `env:"VELOCITY_POSTS_PER_DAY" envDefault:"50"`
+	NewAccountPostsPerDay       int           `env:"VELOCITY_NEW_ACCOUNT_POSTS_PER_DAY" envDefault:"10"`
+	CommentsPerMinute           int           `env:"VELOCITY_COMMENTS_PER_MINUTE" envDefault:"10"`
+	NewAccountCommentsPerMinute int           `env:"VELOCITY_NEW_ACCOUNT_COMMENTS_PER_MINUTE" envDefault:"3"`
+}
+
+type Stats struct {
+	Interval   time.Duration `env:"STATS_INTERVAL" envDefault:"5m"`
+	SignupDays int           `env:"STATS_SIGNUP_DAYS" envDefault:"30"`
+	TopTags    int           `env:"STATS_TOP_TAGS" envDefault:"10"`
 }
 
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+)
+
+// Cache sets how long each kind of entry is cached. Only the first page of a
+// feed with the default pagination is cached. The memory backend keeps up to
+// MaxEntries values in process, for single-node deployments without Redis.
+type Cache struct {
+	Backend         string        `env:"CACHE_BACKEND" envDefault:"redis"`
+	MaxEntries      int           `env:"CACHE_MAX_ENTRIES" envDefault:"10000"`
+	UserTTL         time.Duration `env:"CACHE_USER_TTL" envDefault:"1m"`
+	PostTTL         time.Duration `env:"CACHE_POST_TTL" envDefault:"5m"`
+	CommentCountTTL time.Duration `env:"CACHE_COMMENT_COUNT_TTL" envDefault:"1m"`
+	FeedTTL         time.Duration `env:"CACHE_FEED_TTL" envDefault:"30s"`
+}
+
+// Timeline bounds the home timelines kept in Redis. Posts by accounts with
+// more than FanOutLimit followers are not copied into timelines; readers pick
+// them up at request time instead. Timelines nobody reads expire after TTL.
+type Timeline struct {
+	MaxLength   int           `env:"TIMELINE_MAX_LENGTH" envDefault:"800"`
+	FanOutLimit int           `env:"TIMELINE_FAN_OUT_LIMIT" envDefault:"10000"`
+	TTL         time.Duration `env:"TIMELINE_TTL" envDefault:"168h"`
+}
+
+// Redis is only used when Enabled, and its connection settings are only
+// required then. HealthInterval is how often an unreachable server is pinged
+// to switch back from degraded mode.
 type Redis struct {
-	Addr    string `env:"REDIS_ADDR,required"`
-	PW      string `env:"REDIS_PASSWORD,required"`
-	DB      int    `env:"REDIS_DB,required"`
-	Enabled bool   `env:"REDIS_ENABLED,required"`
+	Addr           string        `env:"REDIS_ADDR"`
+	PW             string        `env:"REDIS_PASSWORD"`
+	DB             int           `env:"REDIS_DB"`
+	Enabled        bool          `env:"REDIS_ENABLED,required"`
+	HealthInterval time.Duration `env:"REDIS_HEALTH_INTERVAL" envDefault:"5s"`
 }
 
 type Mail struct {
@@ -60,6 +159,8 @@ type Mail struct {
 	FromName            string        `env:"MAIL_FROM_NAME,required"`
 	MaxRetries          uint          `env:"MAIL_MAX_RETRIES,required"`
 	UserWelcomeTemplate string        `env:"TOKEN_USER_WELCOME_TEMPLATE,required"`
+	EmailChangeTemplate string        `env:"EMAIL_CHANGE_TEMPLATE" envDefault:"email_change_confirm.tmpl"`
+	EmailNoticeTemplate string        `env:"EMAIL_CHANGE_NOTICE_TEMPLATE" envDefault:"email_change_notice.tmpl"`
 	FromEmail           string        `env:"FROM_EMAIL,required"`
 	ApiKey              string        `env:"API_KEY,required"`
 	FrontendURL         string        `env:"FRONTEND_URL,required"`
@@ -78,6 +179,12 @@ type DBConfig struct {
 	MaxIdleConns int           `env:"DB_MAX_IDLE_CONNECTIONS,required"`
 	MaxIdleTime  time.Duration `env:"DB_MAX_IDLE_TIME,required"`
 	Timeout      time.Duration `env:"DB_TIMEOUT,required"`
+	// ReplicaSources are connection strings of read replicas. After a user
+	// writes, their reads stay on the primary for ReadYourWritesWindow so
+	// replication lag cannot hide the write from them.
+	ReplicaSources        []string      `env:"DB_REPLICA_SOURCES" envSeparator:";"`
+	ReplicaHealthInterval time.Duration `env:"DB_REPLICA_HEALTH_INTERVAL" envDefault:"5s"`
+	ReadYourWritesWindow  time.Duration `env:"DB_READ_YOUR_WRITES_WINDOW" envDefault:"5s"`
 }
 
 type Pagination struct {
@@ -138,12 +245,26 @@ func LoadingConfig() error {
 	if err := env.Parse(authConfig); err != nil {
 		log.Fatal("error parsing token config")
 	}
+	if authConfig.LegacyTokensUntil != "" {
+		cutoff, err := time.Parse(time.RFC3339, authConfig.LegacyTokensUntil)
+		if err != nil {
+			log.Fatalf("invalid AUTH_LEGACY_TOKENS_UNTIL %q", authConfig.LegacyTokensUntil)
+		}
+		authConfig.LegacyTokensCutoff = cutoff
+	}
 	config.Authentication = *authConfig
 
 	redisConfig := &Redis{}
 	if err := env.Parse(redisConfig); err != nil {
 		log.Fatal("error parsing token config")
 	}
+	if redisConfig.Enabled {
+		for _, key := range []string{"REDIS_ADDR", "REDIS_PASSWORD", "REDIS_DB"} {
+			if _, ok := os.LookupEnv(key); !ok {
+				log.Fatalf("%s is required when REDIS_ENABLED is true", key)
+			}
+		}
+	}
 	config.Redis = *redisConfig
 
 	rateConfig := &Rate{}
@@ -151,8 +272,69 @@ func LoadingConfig() error {
 		log.Fatal("error parsing token config")
 	}
 
+	switch rateConfig.Algorithm {
+	case RateAlgorithmFixedWindow, RateAlgorithmSlidingWindow, RateAlgorithmGCRA:
+	default:
+		log.Fatalf("unknown rate limit algorithm %q", rateConfig.Algorithm)
+	}
+
 	config.Rate = *rateConfig
 
+	retentionConfig := &Retention{}
+	if err := env.Parse(retentionConfig); err != nil {
+		log.Fatal("error parsing retention config")
+	}
+
+	config.Retention = *retentionConfig
+
+	moderationConfig := &Moderation{}
+	if err := env.Parse(moderationConfig); err != nil {
+		log.Fatal("error parsing moderation config")
+	}
+
+	config.Moderation = *moderationConfig
+
+	contentPolicyConfig := &ContentPolicy{}
+	if err := env.Parse(contentPolicyConfig); err != nil {
+		log.Fatal("error parsing content policy config")
+	}
+
+	config.ContentPolicy = *contentPolicyConfig
+
+	velocityConfig := &Velocity{}
+	if err := env.Parse(velocityConfig); err != nil {
+		log.Fatal("error parsing velocity config")
+	}
+
+	config.Velocity = *velocityConfig
+
+	statsConfig := &Stats{}
+	if err := env.Parse(statsConfig); err != nil {
+		log.Fatal("error parsing stats config")
+	}
+
+	config.Stats = *statsConfig
+
+	cacheConfig := &Cache{}
+	if err := env.Parse(cacheConfig); err != nil {
+		log.Fatal("error parsing cache config")
+	}
+
+	switch cacheConfig.Backend {
+	case CacheBackendRedis, CacheBackendMemory:
+	default:
+		log.Fatalf("unknown cache backend %q", cacheConfig.Backend)
+	}
+
+	config.Cache = *cacheConfig
+
+	timelineConfig := &Timeline{}
+	if err := env.Parse(timelineConfig); err != nil {
+		log.Fatal("error parsing timeline config")
+	}
+
+	config.Timeline = *timelineConfig
+
 	AppConfig = config
 
 	return nil
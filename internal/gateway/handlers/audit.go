@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/logger"
+	"net"
+	"net/http"
+)
+
+type RequestIDKey string
+
+const RequestIDCTX RequestIDKey = "request_id"
+
+type ClientIPKey string
+
+const ClientIPCTX ClientIPKey = "client_ip"
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// GetAuditLogsHandler lists audit log entries.
+//
+//	@Summary		Fetches the audit log
+//	@Description	Lists audit log entries, newest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			actor_id	query		int		false	"Actor ID"
+//	@Param			action		query		string	false	"Action"
+//	@Param			target_type	query		string	false	"Target type"
+//	@Param			target_id	query		int		false	"Target ID"
+//	@Param			since		query		string	false	"RFC3339 lower bound"
+//	@Param			until		query		string	false	"RFC3339 upper bound"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Success		200			{object}	[]service_models.AuditLog
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/audit [get]
+func (a *AuditHandler) GetAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	q := service_models.AuditQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = helper.Validate.Struct(q); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logs, err := a.auditService.GetAll(r.Context(), q)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, logs); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// newAuditLog prepares an audit entry for the current request, attributing it
+// to the authenticated user when there is one.
+func newAuditLog(r *http.Request, action, targetType string, targetId int64) *service_models.AuditLog {
+	log := &service_models.AuditLog{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   &targetId,
+		IP:         GetClientIPFromContext(r),
+		RequestID:  GetRequestIDFromContext(r),
+	}
+	if user := GetUserFromContext(r); user != nil {
+		log.ActorID = &user.ID
+	}
+	return log
+}
+
+// recordAudit stores an audit entry without failing the request it belongs to.
+func recordAudit(auditService service.AuditService, log *service_models.AuditLog, before, after any) {
+	if err := auditService.Record(context.Background(), log, before, after); err != nil {
+		logger.Logger.Error("error recording audit log", "action", log.Action, "request_id", log.RequestID, "error", err.Error())
+	}
+}
+
+func GetRequestIDFromContext(r *http.Request) string {
+	requestId, _ := r.Context().Value(RequestIDCTX).(string)
+	return requestId
+}
+
+// GetClientIPFromContext returns the client address resolved by the ClientIP
+// middleware, or the connection's address when the request did not pass
+// through it.
+func GetClientIPFromContext(r *http.Request) string {
+	if ip, ok := r.Context().Value(ClientIPCTX).(string); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
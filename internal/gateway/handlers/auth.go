@@ -4,8 +4,8 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
@@ -15,13 +15,15 @@ import (
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
 	"github.com/saleh-ghazimoradi/Gophergram/logger"
 	"net/http"
-	"time"
 )
 
 type AuthHandler struct {
-	userService service.UserService
-	mailService service.Mailer
-	authService service.Authenticator
+	userService       service.UserService
+	mailService       service.Mailer
+	authService       service.Authenticator
+	sessionService    service.SessionService
+	auditService      service.AuditService
+	suspensionService service.SuspensionService
 }
 
 // RegisterUserHandler Register a user
@@ -63,7 +65,7 @@ func (a *AuthHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Request
 	hash := sha256.Sum256([]byte(plainToken))
 	hashToken := hex.EncodeToString(hash[:])
 
-	if err := a.userService.CreateAndInvite(context.Background(), user, hashToken, config.AppConfig.Mail.Exp); err != nil {
+	if err := a.userService.CreateAndInvite(r.Context(), user, hashToken, config.AppConfig.Mail.Exp); err != nil {
 		switch err {
 		case repository.ErrDuplicateEmail:
 			helper.BadRequestResponse(w, r, err)
@@ -95,7 +97,7 @@ func (a *AuthHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Request
 		logger.Logger.Error("error sending welcome email", "error", err)
 
 		// rollback user creation if email fails (SAGA pattern)
-		if err := a.userService.Delete(context.Background(), user.ID); err != nil {
+		if err := a.userService.Delete(r.Context(), user.ID); err != nil {
 			logger.Logger.Error("error deleting user", "error", err)
 		}
 		helper.InternalServerError(w, r, err)
@@ -134,7 +136,7 @@ func (a *AuthHandler) CreateTokenHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	user, err := a.userService.GetByEmail(context.Background(), payload.Email)
+	user, err := a.userService.GetByEmail(r.Context(), payload.Email)
 	if err != nil {
 		switch err {
 		case repository.ErrsNotFound:
@@ -145,30 +147,77 @@ func (a *AuthHandler) CreateTokenHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	claims := jwt.MapClaims{
-		"sub": user.ID,
-		"exp": time.Now().Add(config.AppConfig.Mail.Exp).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"iss": config.AppConfig.Authentication.Iss,
-		"aud": config.AppConfig.Authentication.Aud,
+	matches, err := user.Password.Matches(payload.Password)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if !matches {
+		log := newAuditLog(r, "auth.login.failed", "user", user.ID)
+		log.ActorID = &user.ID
+		if err = a.auditService.RecordLoginFailure(context.Background(), log); err != nil {
+			logger.Logger.Error("error recording failed login", "user_id", user.ID, "error", err.Error())
+		}
+		helper.UnauthorizedErrorResponse(w, r, repository.ErrInvalidPassword)
+		return
+	}
+
+	suspension, err := a.suspensionService.GetActive(r.Context(), user.ID)
+	switch {
+	case err == nil:
+		helper.AccountSuspendedResponse(w, r, suspension.ExpiresAt)
+		return
+	case !errors.Is(err, repository.ErrsNotFound):
+		helper.InternalServerError(w, r, err)
+		return
 	}
 
+	session := &service_models.Session{
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IP:        GetClientIPFromContext(r),
+	}
+
+	if err = a.sessionService.Create(r.Context(), session); err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	claims := service.SessionClaims(user.ID, session.ID, config.AppConfig.Mail.Exp)
+
 	token, err := a.authService.GenerateToken(claims)
 	if err != nil {
 		helper.InternalServerError(w, r, err)
 		return
 	}
 
+	// The failures since the last login go into this entry, since only the
+	// first of them was audited on its own.
+	var after any
+	failures, err := a.auditService.ClearLoginFailures(context.Background(), user.ID)
+	if err != nil {
+		logger.Logger.Error("error clearing failed logins", "user_id", user.ID, "error", err.Error())
+	} else if failures > 0 {
+		after = map[string]int{"failed_attempts": failures}
+	}
+
+	log := newAuditLog(r, "auth.login", "session", session.ID)
+	log.ActorID = &user.ID
+	recordAudit(a.auditService, log, nil, after)
+
 	if err := json.JSONResponse(w, http.StatusCreated, token); err != nil {
 		helper.InternalServerError(w, r, err)
 	}
 }
 
-func NewAuthHandler(userService service.UserService, mailService service.Mailer, authService service.Authenticator) *AuthHandler {
+func NewAuthHandler(userService service.UserService, mailService service.Mailer, authService service.Authenticator, sessionService service.SessionService, auditService service.AuditService, suspensionService service.SuspensionService) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
-		mailService: mailService,
-		authService: authService,
+		userService:       userService,
+		mailService:       mailService,
+		authService:       authService,
+		sessionService:    sessionService,
+		auditService:      auditService,
+		suspensionService: suspensionService,
 	}
 }
@@ -1,8 +1,6 @@
 package handlers
 
 import (
-	"context"
-	"fmt"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
@@ -25,6 +23,10 @@ type FeedHandler struct {
 //	@Param			limit	query		int		false	"Limit"
 //	@Param			offset	query		int		false	"Offset"
 //	@Param			sort	query		string	false	"Sort"
+//	@Param			search	query		string	false	"Search in title and content"
+//	@Param			tags	query		string	false	"Comma separated tags"
+//	@Param			since	query		string	false	"Only posts created after this time"
+//	@Param			until	query		string	false	"Only posts created before this time"
 //	@Success		200		{object}	[]service_models.PostFeed
 //	@Failure		400		{object}	error
 //	@Failure		500		{object}	error
@@ -37,8 +39,6 @@ func (f *FeedHandler) GetUserFeedHandler(w http.ResponseWriter, r *http.Request)
 		Sort:   config.AppConfig.Pagination.Sort,
 	}
 
-	fmt.Printf("limit: %d, type: %T, offset: %d, type: %T\n", p.Limit, p.Limit, p.Offset, p.Offset)
-
 	fq, err := p.Parse(r)
 	if err != nil {
 		helper.BadRequestResponse(w, r, err)
@@ -50,10 +50,9 @@ func (f *FeedHandler) GetUserFeedHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	//user := getUserFromContext(r)
-	//fmt.Println(user.ID)
+	user := GetUserFromContext(r)
 
-	feed, err := f.postService.GetUserFeed(context.Background(), int64(10), fq)
+	feed, err := f.postService.GetUserFeed(r.Context(), user.ID, fq)
 	if err != nil {
 		helper.InternalServerError(w, r, err)
 		return
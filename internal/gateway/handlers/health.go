@@ -4,10 +4,13 @@ import (
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 	"net/http"
 )
 
-type HealthHandler struct{}
+type HealthHandler struct {
+	redis *utils.RedisClient
+}
 
 // Health provides the health status of the application.
 //
@@ -22,12 +25,27 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		"status":  "ok",
 		"env":     config.AppConfig.ServerConfig.Env,
 		"version": config.AppConfig.ServerConfig.Version,
+		"redis":   h.redisStatus(),
 	}
 	if err := json.JSONResponse(w, http.StatusOK, data); err != nil {
 		helper.InternalServerError(w, r, err)
 	}
 }
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+// redisStatus is "degraded" while the app runs without an enabled Redis.
+func (h *HealthHandler) redisStatus() string {
+	switch {
+	case !config.AppConfig.Redis.Enabled:
+		return "disabled"
+	case h.redis.Healthy():
+		return "ok"
+	default:
+		return "degraded"
+	}
+}
+
+func NewHealthHandler(redis *utils.RedisClient) *HealthHandler {
+	return &HealthHandler{
+		redis: redis,
+	}
 }
@@ -8,7 +8,11 @@ import (
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/logger"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type PostKey string
@@ -18,12 +22,14 @@ const PostCtx PostKey = "post"
 type PostHandler struct {
 	postService    service.PostService
 	commentService service.CommentService
+	auditService   service.AuditService
+	contentPolicy  service.ContentPolicyService
 }
 
 // CreatePostHandler handles creating a new post.
 //
 //	@Summary		Creates a post
-//	@Description	Creates a post
+//	@Description	Creates a post. Content that breaks the content policy is rejected with 422.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -31,6 +37,8 @@ type PostHandler struct {
 //	@Success		201		{object}	service_models.Post
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
+//	@Failure		422		{object}	error
+//	@Failure		429		{object}	error
 //	@Failure		500		{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/v1/posts [post]
@@ -55,11 +63,26 @@ func (p *PostHandler) CreatePostHandler(w http.ResponseWriter, r *http.Request)
 		UserID:  user.ID,
 	}
 
-	if err := p.postService.Create(context.Background(), post); err != nil {
-		helper.InternalServerError(w, r, err)
+	content := &service_models.Content{
+		Type:   service_models.ReportTargetPost,
+		UserID: user.ID,
+		Title:  post.Title,
+		Body:   post.Content,
+	}
+
+	evaluation, ok := p.evaluateContent(w, r, content)
+	if !ok {
 		return
 	}
 
+	if err := p.postService.Create(r.Context(), post); err != nil {
+		createErrorResponse(w, r, err)
+		return
+	}
+
+	content.ID = post.ID
+	p.flagContent(content, evaluation)
+
 	if err := json.JSONResponse(w, http.StatusCreated, post); err != nil {
 		helper.InternalServerError(w, r, err)
 	}
@@ -68,20 +91,23 @@ func (p *PostHandler) CreatePostHandler(w http.ResponseWriter, r *http.Request)
 // GetPostByIdHandler retrieves a specific post by ID.
 //
 //	@Summary		Fetches a post
-//	@Description	Fetches a post by ID
+//	@Description	Fetches a post by ID. Answers 304 when If-None-Match shows the client's copy is current.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Post ID"
-//	@Success		200	{object}	service_models.Post
-//	@Failure		404	{object}	error
+//	@Param			id					path		int		true	"Post ID"
+//	@Param			If-None-Match		header		string	false	"ETag of the cached copy"
+//	@Success		200					{object}	service_models.Post
+//	@Success		304					{string}	string	"Not modified"
+//	@Failure		404					{object}	error
 //	@Failure		500	{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/v1/posts/{id} [get]
 func (p *PostHandler) GetPostByIdHandler(w http.ResponseWriter, r *http.Request) {
 	post := GetPostFromCTX(r)
+	user := GetUserFromContext(r)
 
-	comments, err := p.commentService.GetByPostId(context.Background(), post.ID)
+	comments, err := p.commentService.GetByPostId(r.Context(), post.ID, user.ID)
 	if err != nil {
 		helper.InternalServerError(w, r, err)
 		return
@@ -89,30 +115,65 @@ func (p *PostHandler) GetPostByIdHandler(w http.ResponseWriter, r *http.Request)
 
 	post.Comments = comments
 
+	etag, err := postETag(post)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	// No Last-Modified: deleted, hidden or edited comments change the body
+	// without moving any timestamp the post has.
+	if helper.NotModified(w, r, etag, time.Time{}) {
+		return
+	}
+
 	if err = json.JSONResponse(w, http.StatusOK, post); err != nil {
 		helper.InternalServerError(w, r, err)
 	}
 }
 
+// postETag leads with the post's version, which If-Match compares on its
+// own, followed by a digest of the whole response including comments.
+func postETag(post *service_models.Post) (string, error) {
+	return helper.ETag(strconv.Itoa(post.Version), post)
+}
+
+// matchesPostVersion reports whether etag was issued for the post's current
+// version. New comments do not change the version, so they do not fail an
+// edit.
+func matchesPostVersion(etag string, post *service_models.Post) bool {
+	version, _, _ := strings.Cut(strings.Trim(etag, `"`), "-")
+	return version == strconv.Itoa(post.Version)
+}
+
 // UpdatePostHandler updates an existing post.
 //
 //	@Summary		Updates a post
-//	@Description	Updates a post by ID
+//	@Description	Updates a post by ID. With If-Match, the update only goes ahead if the post is still at the version the ETag was issued for.
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		int									true	"Post ID"
-//	@Param			payload	body		service_models.UpdatePostPayload	true	"Post payload"
-//	@Success		200		{object}	service_models.Post
-//	@Failure		400		{object}	error
-//	@Failure		401		{object}	error
-//	@Failure		404		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			id			path		int									true	"Post ID"
+//	@Param			If-Match	header		string								false	"ETag of the version being edited"
+//	@Param			payload		body		service_models.UpdatePostPayload	true	"Post payload"
+//	@Success		200			{object}	service_models.Post
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		412			{object}	error
+//	@Failure		422			{object}	error
+//	@Failure		500			{object}	error
 //	@Security		ApiKeyAuth
 //	@Router			/v1/posts/{id} [patch]
 func (p *PostHandler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := GetPostFromCTX(r)
 
+	if !helper.IfMatch(r, func(etag string) bool { return matchesPostVersion(etag, post) }) {
+		helper.PreconditionFailedResponse(w, r, repository.ErrEditConflict)
+		return
+	}
+
 	var payload service_models.UpdatePostPayload
 	if err := json.ReadJSON(w, r, &payload); err != nil {
 		helper.BadRequestResponse(w, r, err)
@@ -124,6 +185,8 @@ func (p *PostHandler) UpdatePostHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before := map[string]string{"title": post.Title, "content": post.Content}
+
 	if payload.Title != nil {
 		post.Title = *payload.Title
 	}
@@ -132,16 +195,217 @@ func (p *PostHandler) UpdatePostHandler(w http.ResponseWriter, r *http.Request)
 		post.Content = *payload.Content
 	}
 
-	if err := p.postService.Update(context.Background(), post); err != nil {
-		helper.InternalServerError(w, r, err)
+	content := &service_models.Content{
+		Type:   service_models.ReportTargetPost,
+		ID:     post.ID,
+		UserID: post.UserID,
+		Title:  post.Title,
+		Body:   post.Content,
+	}
+
+	evaluation, ok := p.evaluateContent(w, r, content)
+	if !ok {
 		return
 	}
 
+	user := GetUserFromContext(r)
+
+	if err := p.postService.Update(r.Context(), post, user.ID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		case errors.Is(err, repository.ErrEditConflict) && r.Header.Get("If-Match") != "":
+			helper.PreconditionFailedResponse(w, r, err)
+		case errors.Is(err, repository.ErrEditConflict):
+			helper.ConflictResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	p.flagContent(content, evaluation)
+
+	if user.ID != post.UserID {
+		after := map[string]string{"title": post.Title, "content": post.Content}
+		recordAudit(p.auditService, newAuditLog(r, "post.update", "post", post.ID), before, after)
+	}
+
+	if etag, err := postETag(post); err == nil {
+		w.Header().Set("ETag", etag)
+	}
+
 	if err := json.JSONResponse(w, http.StatusOK, post); err != nil {
 		helper.InternalServerError(w, r, err)
 	}
 }
 
+// GetPostRevisionsHandler lists the earlier versions of a post, or diffs two of them.
+//
+//	@Summary		Fetches the edit history of a post
+//	@Description	Lists earlier versions of a post, newest first. With both from and to set, returns a line diff between those versions instead; either may be the current version.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			id		path		int	true	"Post ID"
+//	@Param			from	query		int	false	"Older version"
+//	@Param			to		query		int	false	"Newer version"
+//	@Success		200		{object}	[]service_models.PostRevision
+//	@Success		200		{object}	service_models.PostRevisionDiff
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/posts/{id}/revisions [get]
+func (p *PostHandler) GetPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post := GetPostFromCTX(r)
+
+	qs := r.URL.Query()
+	if qs.Get("from") == "" && qs.Get("to") == "" {
+		revisions, err := p.postService.GetRevisions(r.Context(), post.ID)
+		if err != nil {
+			helper.InternalServerError(w, r, err)
+			return
+		}
+
+		if err = json.JSONResponse(w, http.StatusOK, revisions); err != nil {
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	from, err := strconv.Atoi(qs.Get("from"))
+	if err != nil {
+		helper.BadRequestResponse(w, r, errors.New("from must be a version number"))
+		return
+	}
+
+	to, err := strconv.Atoi(qs.Get("to"))
+	if err != nil {
+		helper.BadRequestResponse(w, r, errors.New("to must be a version number"))
+		return
+	}
+
+	diff, err := p.postService.DiffRevisions(r.Context(), post, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, diff); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// CreateCommentHandler adds a comment to a post.
+//
+//	@Summary		Comments on a post
+//	@Description	Adds a comment to a post. Content that breaks the content policy is rejected with 422.
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int									true	"Post ID"
+//	@Param			payload	body		service_models.CreateCommentPayload	true	"Comment payload"
+//	@Success		201		{object}	service_models.Comment
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		422		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/posts/{id}/comments [post]
+func (p *PostHandler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	post := GetPostFromCTX(r)
+
+	var payload service_models.CreateCommentPayload
+	if err := json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	content := &service_models.Content{
+		Type:   service_models.ReportTargetComment,
+		UserID: user.ID,
+		Body:   payload.Content,
+	}
+
+	evaluation, ok := p.evaluateContent(w, r, content)
+	if !ok {
+		return
+	}
+
+	comment := &service_models.Comment{
+		PostID:  post.ID,
+		UserID:  user.ID,
+		Content: payload.Content,
+		User: service_models.User{
+			ID:       user.ID,
+			Username: user.Username,
+		},
+	}
+
+	if err := p.commentService.Create(r.Context(), comment); err != nil {
+		createErrorResponse(w, r, err)
+		return
+	}
+
+	content.ID = comment.ID
+	p.flagContent(content, evaluation)
+
+	if err := json.JSONResponse(w, http.StatusCreated, comment); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// evaluateContent runs the content policy and writes the 422 response when the
+// content is rejected. It reports whether the request may go on.
+func (p *PostHandler) evaluateContent(w http.ResponseWriter, r *http.Request, content *service_models.Content) (*service_models.ContentEvaluation, bool) {
+	evaluation, err := p.contentPolicy.Evaluate(r.Context(), content)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return nil, false
+	}
+
+	if evaluation.Rejected() {
+		helper.ContentRejectedResponse(w, r, evaluation.Rejection.Rule, evaluation.Rejection.Reason)
+		return nil, false
+	}
+	return evaluation, true
+}
+
+// flagContent queues stored content for review when a rule flagged it.
+func (p *PostHandler) flagContent(content *service_models.Content, evaluation *service_models.ContentEvaluation) {
+	if len(evaluation.Flags) == 0 {
+		return
+	}
+	if err := p.contentPolicy.Flag(context.Background(), content, evaluation.Flags); err != nil {
+		logger.Logger.Error("error flagging content", "type", content.Type, "id", content.ID, "error", err.Error())
+	}
+}
+
+// createErrorResponse answers a failed post or comment creation, telling the
+// author when they have hit their velocity limit.
+func createErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var velocityErr *service.VelocityError
+	if errors.As(err, &velocityErr) {
+		helper.ActionLimitExceededResponse(w, r, velocityErr, velocityErr.RetryAfter)
+		return
+	}
+	helper.InternalServerError(w, r, err)
+}
+
 // DeletePostHandler deletes a post by ID.
 //
 //	@Summary		Deletes a post
@@ -158,14 +422,90 @@ func (p *PostHandler) UpdatePostHandler(w http.ResponseWriter, r *http.Request)
 func (p *PostHandler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 	post := GetPostFromCTX(r)
 
-	if err := p.postService.Delete(context.Background(), post.ID); err != nil {
+	if err := p.postService.Delete(r.Context(), post.ID); err != nil {
 		switch {
 		case errors.Is(err, repository.ErrsNotFound):
 			helper.NotFoundResponse(w, r, err)
 		default:
 			helper.InternalServerError(w, r, err)
 		}
+		return
 	}
+
+	recordAudit(p.auditService, newAuditLog(r, "post.delete", "post", post.ID), map[string]any{"user_id": post.UserID, "title": post.Title}, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestorePostHandler restores a deleted post.
+//
+//	@Summary		Restores a post
+//	@Description	Restores a soft-deleted post by ID
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"Post ID"
+//	@Success		204	{object}	string
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/posts/{id}/restore [put]
+func (p *PostHandler) RestorePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = p.postService.Restore(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(p.auditService, newAuditLog(r, "post.restore", "post", id), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreCommentHandler restores a deleted comment.
+//
+//	@Summary		Restores a comment
+//	@Description	Restores a soft-deleted comment by ID
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"Comment ID"
+//	@Success		204	{object}	string
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/comments/{id}/restore [put]
+func (p *PostHandler) RestoreCommentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = p.commentService.Restore(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(p.auditService, newAuditLog(r, "comment.restore", "comment", id), nil, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -174,9 +514,11 @@ func GetPostFromCTX(r *http.Request) *service_models.Post {
 	return post
 }
 
-func NewPostHandler(postServer service.PostService, commentService service.CommentService) *PostHandler {
+func NewPostHandler(postServer service.PostService, commentService service.CommentService, auditService service.AuditService, contentPolicy service.ContentPolicyService) *PostHandler {
 	return &PostHandler{
 		postService:    postServer,
 		commentService: commentService,
+		auditService:   auditService,
+		contentPolicy:  contentPolicy,
 	}
 }
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+type ReportHandler struct {
+	reportService service.ReportService
+	auditService  service.AuditService
+}
+
+// CreateReportHandler reports a post, comment or account.
+//
+//	@Summary		Reports content
+//	@Description	Reports an abusive post, comment or account for moderator review
+//	@Tags			reports
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		service_models.CreateReportPayload	true	"Report payload"
+//	@Success		201		{object}	service_models.Report
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/reports [post]
+func (h *ReportHandler) CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	var payload service_models.CreateReportPayload
+	if err := json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	report := &service_models.Report{
+		ReporterID: &user.ID,
+		TargetType: payload.TargetType,
+		TargetID:   payload.TargetID,
+		Reason:     payload.Reason,
+	}
+
+	if err := h.reportService.Create(r.Context(), report); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		case errors.Is(err, repository.ErrsConflict):
+			helper.ConflictResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := json.JSONResponse(w, http.StatusCreated, report); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// GetReportQueueHandler lists reported content grouped by target.
+//
+//	@Summary		Fetches the moderation queue
+//	@Description	Lists reported content, grouping every report against the same target
+//	@Tags			moderation
+//	@Produce		json
+//	@Param			status		query		string	false	"open, dismissed or resolved"
+//	@Param			target_type	query		string	false	"post, comment or user"
+//	@Param			min_reports	query		int		false	"Minimum number of reports"
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Success		200			{object}	[]service_models.ReportGroup
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/moderation/reports [get]
+func (h *ReportHandler) GetReportQueueHandler(w http.ResponseWriter, r *http.Request) {
+	q := service_models.ReportQueueQuery{
+		Limit:      20,
+		Offset:     0,
+		Status:     service_models.ReportStatusOpen,
+		MinReports: 1,
+	}
+
+	q, err := q.Parse(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = helper.Validate.Struct(q); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	queue, err := h.reportService.GetQueue(r.Context(), q)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, queue); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// ResolveReportsHandler resolves every open report against a target.
+//
+//	@Summary		Resolves reports
+//	@Description	Dismisses the reports against a target, or hides or deletes the content, or suspends its author
+//	@Tags			moderation
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		service_models.ResolveReportsPayload	true	"Resolution payload"
+//	@Success		204		{object}	string
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/moderation/reports/resolve [post]
+func (h *ReportHandler) ResolveReportsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload service_models.ResolveReportsPayload
+	if err := json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	moderator := GetUserFromContext(r)
+
+	if err := h.reportService.Resolve(r.Context(), payload.TargetType, payload.TargetID, payload.Action, moderator); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		case errors.Is(err, repository.ErrInvalidAction), errors.Is(err, repository.ErrSuspendSelf):
+			helper.BadRequestResponse(w, r, err)
+		case errors.Is(err, repository.ErrOutranked):
+			helper.ForbiddenResponse(w, r)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(h.auditService, newAuditLog(r, "report.resolve", payload.TargetType, payload.TargetID), nil, map[string]string{"action": payload.Action})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func NewReportHandler(reportService service.ReportService, auditService service.AuditService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		auditService:  auditService,
+	}
+}
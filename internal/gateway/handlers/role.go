@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+type RoleHandler struct {
+	roleService  service.RoleService
+	auditService service.AuditService
+}
+
+// GetRolesHandler lists every role with its permissions.
+//
+//	@Summary		Lists roles
+//	@Description	Lists every role with its permissions
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	[]service_models.Role
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/roles [get]
+func (h *RoleHandler) GetRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.GetAll(r.Context())
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, roles); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// CreateRoleHandler creates a custom role.
+//
+//	@Summary		Creates a role
+//	@Description	Creates a custom role with the given permissions
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		service_models.CreateRolePayload	true	"Role payload"
+//	@Success		201		{object}	service_models.Role
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/roles [post]
+func (h *RoleHandler) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload service_models.CreateRolePayload
+	if err := json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	role := &service_models.Role{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Level:       payload.Level,
+		Permissions: payload.Permissions,
+	}
+
+	if err := h.roleService.Create(r.Context(), role); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsConflict):
+			helper.ConflictResponse(w, r, err)
+		case errors.Is(err, repository.ErrUnknownPermission):
+			helper.BadRequestResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(h.auditService, newAuditLog(r, "role.create", "role", role.ID), nil, role)
+
+	if err := json.JSONResponse(w, http.StatusCreated, role); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// UpdateRoleHandler edits a role.
+//
+//	@Summary		Updates a role
+//	@Description	Updates the description, level or permissions of a role
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int									true	"Role ID"
+//	@Param			payload	body		service_models.UpdateRolePayload	true	"Role payload"
+//	@Success		200		{object}	service_models.Role
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/roles/{id} [patch]
+func (h *RoleHandler) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	var payload service_models.UpdateRolePayload
+	if err = json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	role, err := h.roleService.GetById(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	before := *role
+
+	if payload.Description != nil {
+		role.Description = *payload.Description
+	}
+
+	if payload.Level != nil {
+		role.Level = *payload.Level
+	}
+
+	role.Permissions = payload.Permissions
+
+	if err = h.roleService.Update(r.Context(), role); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		case errors.Is(err, repository.ErrUnknownPermission):
+			helper.BadRequestResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(h.auditService, newAuditLog(r, "role.update", "role", role.ID), before, role)
+
+	if err = json.JSONResponse(w, http.StatusOK, role); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// AssignRoleHandler assigns a role to a user.
+//
+//	@Summary		Assigns a role to a user
+//	@Description	Assigns a role to a user and records the change
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"User ID"
+//	@Param			payload	body		service_models.AssignRolePayload	true	"Role payload"
+//	@Success		200		{object}	service_models.RoleAssignment
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/users/{id}/role [put]
+func (h *RoleHandler) AssignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	var payload service_models.AssignRolePayload
+	if err = json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	admin := GetUserFromContext(r)
+
+	assignment, err := h.roleService.AssignToUser(r.Context(), id, payload.Role, &admin.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(h.auditService, newAuditLog(r, "user.role.change", "user", id), map[string]any{"role_id": assignment.OldRoleID}, map[string]any{"role_id": assignment.NewRoleID})
+
+	if err = json.JSONResponse(w, http.StatusOK, assignment); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// RevokeRoleHandler resets a user to the default role.
+//
+//	@Summary		Revokes the role of a user
+//	@Description	Resets a user to the default role and records the change
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	service_models.RoleAssignment
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/users/{id}/role [delete]
+func (h *RoleHandler) RevokeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	admin := GetUserFromContext(r)
+
+	assignment, err := h.roleService.RevokeFromUser(r.Context(), id, &admin.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(h.auditService, newAuditLog(r, "user.role.change", "user", id), map[string]any{"role_id": assignment.OldRoleID}, map[string]any{"role_id": assignment.NewRoleID})
+
+	if err = json.JSONResponse(w, http.StatusOK, assignment); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+func NewRoleHandler(roleService service.RoleService, auditService service.AuditService) *RoleHandler {
+	return &RoleHandler{
+		roleService:  roleService,
+		auditService: auditService,
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+type SessionKey string
+
+const SessionCTX SessionKey = "session"
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+// GetSessionsHandler lists the active sessions of the current user.
+//
+//	@Summary		Lists sessions
+//	@Description	Lists the active sessions of the current user, marking the one used for this request
+//	@Tags			sessions
+//	@Produce		json
+//	@Success		200	{object}	[]service_models.Session
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/users/me/sessions [get]
+func (s *SessionHandler) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !helper.IsMeParam(r) {
+		helper.NotFoundResponse(w, r, repository.ErrsNotFound)
+		return
+	}
+
+	user := GetUserFromContext(r)
+	current := GetSessionFromContext(r)
+
+	sessions, err := s.sessionService.GetByUserId(r.Context(), user.ID)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if current != nil {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == current.ID
+		}
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, sessions); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// RevokeSessionHandler revokes a single session of the current user.
+//
+//	@Summary		Revokes a session
+//	@Description	Revokes a session by ID. Tokens issued for it are rejected afterwards.
+//	@Tags			sessions
+//	@Produce		json
+//	@Param			id	path		int	true	"Session ID"
+//	@Success		204	{object}	string
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/users/me/sessions/{id} [delete]
+func (s *SessionHandler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if !helper.IsMeParam(r) {
+		helper.NotFoundResponse(w, r, repository.ErrsNotFound)
+		return
+	}
+
+	id, err := helper.ReadSessionIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	if err = s.sessionService.Revoke(r.Context(), id, user.ID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessionsHandler revokes every session of the current user except the one used for this request.
+//
+//	@Summary		Revokes all other sessions
+//	@Description	Revokes every session of the current user except the current one
+//	@Tags			sessions
+//	@Produce		json
+//	@Success		204	{object}	string
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/users/me/sessions [delete]
+func (s *SessionHandler) RevokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !helper.IsMeParam(r) {
+		helper.NotFoundResponse(w, r, repository.ErrsNotFound)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	var currentId int64
+	if current := GetSessionFromContext(r); current != nil {
+		currentId = current.ID
+	}
+
+	if err := s.sessionService.RevokeOthers(r.Context(), user.ID, currentId); err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetSessionFromContext(r *http.Request) *service_models.Session {
+	session, _ := r.Context().Value(SessionCTX).(*service_models.Session)
+	return session
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+type ShadowBanHandler struct {
+	userService  service.UserService
+	auditService service.AuditService
+}
+
+// GetShadowBanHandler reports whether a user is shadow-banned.
+//
+//	@Summary		Fetches the shadow-ban state of a user
+//	@Description	Reports whether the posts and comments of a user are hidden from everyone but them
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	service_models.ShadowBan
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/users/{id}/shadow-ban [get]
+func (h *ShadowBanHandler) GetShadowBanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	shadowBan, err := h.userService.GetShadowBan(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, shadowBan); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// ShadowBanUserHandler shadow-bans a user.
+//
+//	@Summary		Shadow-bans a user
+//	@Description	Hides the posts and comments of a user from everyone but them. The user is not told.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	service_models.ShadowBan
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/users/{id}/shadow-ban [put]
+func (h *ShadowBanHandler) ShadowBanUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if GetUserFromContext(r).ID == id {
+		helper.BadRequestResponse(w, r, errors.New("you cannot shadow-ban yourself"))
+		return
+	}
+
+	h.setShadowBan(w, r, id, true)
+}
+
+// LiftShadowBanHandler lifts the shadow-ban of a user.
+//
+//	@Summary		Lifts a shadow-ban
+//	@Description	Makes the posts and comments of a user visible again
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	service_models.ShadowBan
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/users/{id}/shadow-ban [delete]
+func (h *ShadowBanHandler) LiftShadowBanHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	h.setShadowBan(w, r, id, false)
+}
+
+func (h *ShadowBanHandler) setShadowBan(w http.ResponseWriter, r *http.Request, id int64, banned bool) {
+	before, err := h.userService.GetShadowBan(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = h.userService.SetShadowBan(r.Context(), id, banned); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	after := &service_models.ShadowBan{UserID: id, ShadowBanned: banned}
+	if before.ShadowBanned != banned {
+		recordAudit(h.auditService, newAuditLog(r, "user.shadow_ban", "user", id), before, after)
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, after); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+func NewShadowBanHandler(userService service.UserService, auditService service.AuditService) *ShadowBanHandler {
+	return &ShadowBanHandler{
+		userService:  userService,
+		auditService: auditService,
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"net/http"
+)
+
+type StatsHandler struct {
+	statsService service.StatsService
+}
+
+// GetStatsHandler returns the instance statistics.
+//
+//	@Summary		Fetches instance statistics
+//	@Description	Returns active users, daily signups and activations, content totals, the moderation queue size and the top tags. The figures are refreshed periodically.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	service_models.AdminStats
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/stats [get]
+func (s *StatsHandler) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.statsService.Get(r.Context())
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, stats); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+func NewStatsHandler(statsService service.StatsService) *StatsHandler {
+	return &StatsHandler{
+		statsService: statsService,
+	}
+}
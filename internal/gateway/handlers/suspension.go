@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+	"time"
+)
+
+type SuspensionHandler struct {
+	suspensionService service.SuspensionService
+	userService       service.UserService
+	policyService     service.PolicyService
+	auditService      service.AuditService
+}
+
+// SuspendUserHandler suspends or bans a user.
+//
+//	@Summary		Suspends a user
+//	@Description	Suspends a user for a number of hours, or bans them permanently when expires_in_hours is 0. Replaces any active suspension.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int										true	"User ID"
+//	@Param			payload	body		service_models.CreateSuspensionPayload	true	"Suspension payload"
+//	@Success		201		{object}	service_models.Suspension
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/users/{id}/suspension [put]
+func (h *SuspensionHandler) SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	var payload service_models.CreateSuspensionPayload
+	if err = json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err = helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	moderator := GetUserFromContext(r)
+	if moderator.ID == id {
+		helper.BadRequestResponse(w, r, repository.ErrSuspendSelf)
+		return
+	}
+
+	target, err := h.userService.GetById(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := h.policyService.CanManage(r.Context(), moderator, target)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+	if !allowed {
+		helper.ForbiddenResponse(w, r)
+		return
+	}
+
+	duration := time.Duration(payload.ExpiresInHours) * time.Hour
+
+	suspension, err := h.suspensionService.Suspend(r.Context(), id, payload.Reason, duration, &moderator.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(h.auditService, newAuditLog(r, "user.suspend", "user", id), nil, suspension)
+
+	if err = json.JSONResponse(w, http.StatusCreated, suspension); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// LiftSuspensionHandler lifts the active suspension of a user.
+//
+//	@Summary		Lifts a suspension
+//	@Description	Lifts the active suspension or ban of a user
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		204	{object}	string
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/admin/users/{id}/suspension [delete]
+func (h *SuspensionHandler) LiftSuspensionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	moderator := GetUserFromContext(r)
+
+	if err = h.suspensionService.Lift(r.Context(), id, &moderator.ID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	recordAudit(h.auditService, newAuditLog(r, "user.unsuspend", "user", id), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func NewSuspensionHandler(suspensionService service.SuspensionService, userService service.UserService, policyService service.PolicyService, auditService service.AuditService) *SuspensionHandler {
+	return &SuspensionHandler{
+		suspensionService: suspensionService,
+		userService:       userService,
+		policyService:     policyService,
+		auditService:      auditService,
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+	"time"
+)
+
+type TokenKey string
+
+const TokenCTX TokenKey = "personal_access_token"
+
+type PersonalAccessTokenHandler struct {
+	tokenService service.PersonalAccessTokenService
+}
+
+// CreatePersonalAccessTokenHandler creates a personal access token for the current user.
+//
+//	@Summary		Creates a personal access token
+//	@Description	Creates a named, expiring personal access token limited to the given scopes. The token is only returned once.
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		service_models.CreatePersonalAccessTokenPayload	true	"Token payload"
+//	@Success		201		{object}	service_models.PersonalAccessTokenWithSecret
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/user/tokens [post]
+func (p *PersonalAccessTokenHandler) CreatePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload service_models.CreatePersonalAccessTokenPayload
+	if err := json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	token := &service_models.PersonalAccessToken{
+		UserID: user.ID,
+		Name:   payload.Name,
+		Scopes: payload.Scopes,
+		Expiry: time.Now().AddDate(0, 0, payload.ExpiresIn),
+	}
+
+	plainToken, err := p.tokenService.Create(r.Context(), token)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	tokenWithSecret := &service_models.PersonalAccessTokenWithSecret{
+		PersonalAccessToken: token,
+		Token:               plainToken,
+	}
+
+	if err = json.JSONResponse(w, http.StatusCreated, tokenWithSecret); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// GetPersonalAccessTokensHandler lists the personal access tokens of the current user.
+//
+//	@Summary		Lists personal access tokens
+//	@Description	Lists the personal access tokens of the current user without their secrets
+//	@Tags			tokens
+//	@Produce		json
+//	@Success		200	{object}	[]service_models.PersonalAccessToken
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/user/tokens [get]
+func (p *PersonalAccessTokenHandler) GetPersonalAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r)
+
+	tokens, err := p.tokenService.GetByUserId(r.Context(), user.ID)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if err = json.JSONResponse(w, http.StatusOK, tokens); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// DeletePersonalAccessTokenHandler revokes a personal access token of the current user.
+//
+//	@Summary		Revokes a personal access token
+//	@Description	Revokes a personal access token by ID
+//	@Tags			tokens
+//	@Produce		json
+//	@Param			id	path		int	true	"Token ID"
+//	@Success		204	{object}	string
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/user/tokens/{id} [delete]
+func (p *PersonalAccessTokenHandler) DeletePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := helper.ReadIdParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	if err = p.tokenService.Delete(r.Context(), id, user.ID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrsNotFound):
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetTokenFromContext(r *http.Request) *service_models.PersonalAccessToken {
+	token, _ := r.Context().Value(TokenCTX).(*service_models.PersonalAccessToken)
+	return token
+}
+
+func NewPersonalAccessTokenHandler(tokenService service.PersonalAccessTokenService) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		tokenService: tokenService,
+	}
+}
@@ -2,7 +2,12 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
@@ -10,6 +15,7 @@ import (
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
 	"github.com/saleh-ghazimoradi/Gophergram/logger"
 	"net/http"
+	"time"
 )
 
 type UserKey string
@@ -20,18 +26,23 @@ type UserHandler struct {
 	userService     service.UserService
 	followerService service.FollowerService
 	cacheService    service.CacheService
+	mailService     service.Mailer
+	sessionService  service.SessionService
+	auditService    service.AuditService
 }
 
 // GetUserHandler retrieves the current user from the context.
 //
 //	@Summary		Fetches a user profile
-//	@Description	Fetches a user profile by ID
+//	@Description	Fetches a user profile by ID. Answers 304 when If-None-Match shows the client's copy is current.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"id"
-//	@Success		200	{object}	service_models.User
-//	@Failure		400	{object}	error
+//	@Param			id				path		int		true	"id"
+//	@Param			If-None-Match	header		string	false	"ETag of the cached copy"
+//	@Success		200				{object}	service_models.User
+//	@Success		304				{string}	string	"Not modified"
+//	@Failure		400				{object}	error
 //	@Failure		404	{object}	error
 //	@Failure		500	{object}	error
 //	@Security		ApiKeyAuth
@@ -43,7 +54,7 @@ func (u *UserHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := u.getUser(context.Background(), id)
+	user, err := u.getUser(r.Context(), id)
 	if err != nil {
 		switch err {
 		case repository.ErrsNotFound:
@@ -54,28 +65,27 @@ func (u *UserHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users carry no modification time, so the ETag is a digest of the
+	// profile alone.
+	etag, err := helper.ETag("", user)
+	if err != nil {
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	if helper.NotModified(w, r, etag, time.Time{}) {
+		return
+	}
+
 	if err := json.JSONResponse(w, http.StatusOK, user); err != nil {
 		helper.InternalServerError(w, r, err)
 	}
 }
 
 func (u *UserHandler) getUser(ctx context.Context, id int64) (*service_models.User, error) {
-	logger.Logger.Info("cache hit", "key", "user", "id", id)
-	user, err := u.cacheService.Get(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	if user == nil {
-		logger.Logger.Info("fetching from DB", "id", id)
-		user, err = u.userService.GetById(ctx, id)
-		if err != nil {
-			return nil, err
-		}
-		if err = u.cacheService.Set(ctx, user); err != nil {
-			return nil, err
-		}
-	}
-	return user, nil
+	return u.cacheService.Fetch(ctx, id, func(ctx context.Context) (*service_models.User, error) {
+		return u.userService.GetById(ctx, id)
+	})
 }
 
 // FollowUserHandler allows a user to follow another user.
@@ -89,6 +99,7 @@ func (u *UserHandler) getUser(ctx context.Context, id int64) (*service_models.Us
 //	@Success		204	{string}	string	"User followed"
 //	@Failure		400	{object}	error	"User payload missing"
 //	@Failure		404	{object}	error	"User not found"
+//	@Failure		429	{object}	error	"Too many follows"
 //	@Security		ApiKeyAuth
 //	@Router			/v1/users/{id}/follow [put]
 func (u *UserHandler) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -99,8 +110,12 @@ func (u *UserHandler) FollowUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := u.followerService.Follow(context.Background(), followedUser.ID, followedID); err != nil {
+	if err := u.followerService.Follow(r.Context(), followedUser.ID, followedID); err != nil {
+		var velocityErr *service.VelocityError
 		switch {
+		case errors.As(err, &velocityErr):
+			helper.ActionLimitExceededResponse(w, r, velocityErr, velocityErr.RetryAfter)
+			return
 		case errors.Is(err, repository.ErrsConflict):
 			helper.ConflictResponse(w, r, err)
 			return
@@ -137,7 +152,7 @@ func (u *UserHandler) UnFollowUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := u.followerService.Unfollow(context.Background(), followedUser.ID, unfollowedID); err != nil {
+	if err := u.followerService.Unfollow(r.Context(), followedUser.ID, unfollowedID); err != nil {
 		helper.InternalServerError(w, r, err)
 		return
 	}
@@ -166,7 +181,7 @@ func (u *UserHandler) ActivateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = u.userService.Activate(context.Background(), token)
+	err = u.userService.Activate(r.Context(), token)
 	if err != nil {
 		switch err {
 		case repository.ErrsNotFound:
@@ -182,15 +197,238 @@ func (u *UserHandler) ActivateUserHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// ChangePasswordHandler changes the password of the current user
+//
+//	@Summary		Changes the password
+//	@Description	Changes the password of the current user and signs out every other session
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		service_models.ChangePasswordPayload	true	"Passwords"
+//	@Success		204		{string}	string	"Password changed"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/user/password [put]
+func (u *UserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload service_models.ChangePasswordPayload
+	if err := json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	if err := u.userService.ChangePassword(r.Context(), user.ID, payload.CurrentPassword, payload.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidPassword):
+			helper.BadRequestResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	var currentId int64
+	if current := GetSessionFromContext(r); current != nil {
+		currentId = current.ID
+	}
+
+	if err := u.sessionService.RevokeOthers(r.Context(), user.ID, currentId); err != nil {
+		logger.Logger.Error("error revoking sessions after password change", "id", user.ID, "error", err)
+	}
+
+	if err := u.cacheService.Delete(r.Context(), user.ID); err != nil {
+		logger.Logger.Error("error invalidating cached user", "id", user.ID, "error", err)
+	}
+
+	recordAudit(u.auditService, newAuditLog(r, "user.password.change", "user", user.ID), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeEmailHandler starts an email address change for the current user
+//
+//	@Summary		Requests an email change
+//	@Description	Sends a confirmation link to the new address and a cancellation link to the current one. The address only changes once the link is confirmed.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		service_models.ChangeEmailPayload	true	"New email"
+//	@Success		202		{object}	service_models.EmailChange
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/v1/user/email [post]
+func (u *UserHandler) ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var payload service_models.ChangeEmailPayload
+	if err := json.ReadJSON(w, r, &payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if err := helper.Validate.Struct(payload); err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := GetUserFromContext(r)
+
+	confirmToken, confirmHash := newHashedToken()
+	cancelToken, cancelHash := newHashedToken()
+
+	change, err := u.userService.RequestEmailChange(r.Context(), user, payload.Email, confirmHash, cancelHash, config.AppConfig.Mail.Exp)
+	if err != nil {
+		switch err {
+		case repository.ErrDuplicateEmail:
+			helper.BadRequestResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	isProdEnv := config.AppConfig.ServerConfig.Env == "production"
+	confirmVars := struct {
+		Username   string
+		ConfirmURL string
+	}{
+		Username:   user.Username,
+		ConfirmURL: fmt.Sprintf("%s/email/confirm/%s", config.AppConfig.Mail.FrontendURL, confirmToken),
+	}
+
+	if _, err = u.mailService.Send(config.AppConfig.Mail.EmailChangeTemplate, user.Username, change.NewEmail, confirmVars, !isProdEnv); err != nil {
+		logger.Logger.Error("error sending email change confirmation", "error", err)
+
+		// drop the pending change if the confirmation can't be delivered
+		if _, err := u.userService.CancelEmailChange(r.Context(), cancelToken); err != nil {
+			logger.Logger.Error("error cancelling email change", "error", err)
+		}
+		helper.InternalServerError(w, r, err)
+		return
+	}
+
+	noticeVars := struct {
+		Username  string
+		NewEmail  string
+		CancelURL string
+	}{
+		Username:  user.Username,
+		NewEmail:  change.NewEmail,
+		CancelURL: fmt.Sprintf("%s/email/cancel/%s", config.AppConfig.Mail.FrontendURL, cancelToken),
+	}
+
+	if _, err = u.mailService.Send(config.AppConfig.Mail.EmailNoticeTemplate, user.Username, change.OldEmail, noticeVars, !isProdEnv); err != nil {
+		logger.Logger.Error("error sending email change notice", "error", err)
+	}
+
+	if err = json.JSONResponse(w, http.StatusAccepted, change); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// ConfirmEmailChangeHandler switches the email address of a user
+//
+//	@Summary		Confirms an email change
+//	@Description	Switches the email address once the link sent to the new address is followed
+//	@Tags			users
+//	@Produce		json
+//	@Param			token	path		string	true	"token"
+//	@Success		200		{object}	service_models.EmailChange
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/v1/user/email/confirm/{token} [put]
+func (u *UserHandler) ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := helper.ReadTokenParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	change, err := u.userService.ConfirmEmailChange(r.Context(), token)
+	if err != nil {
+		switch err {
+		case repository.ErrsNotFound:
+			helper.NotFoundResponse(w, r, err)
+		case repository.ErrDuplicateEmail:
+			helper.BadRequestResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err = u.cacheService.Delete(r.Context(), change.UserID); err != nil {
+		logger.Logger.Error("error invalidating cached user", "id", change.UserID, "error", err)
+	}
+
+	log := newAuditLog(r, "user.email.change", "user", change.UserID)
+	log.ActorID = &change.UserID
+	recordAudit(u.auditService, log, map[string]string{"email": change.OldEmail}, map[string]string{"email": change.NewEmail})
+
+	if err = json.JSONResponse(w, http.StatusOK, change); err != nil {
+		helper.InternalServerError(w, r, err)
+	}
+}
+
+// CancelEmailChangeHandler cancels a pending email change
+//
+//	@Summary		Cancels an email change
+//	@Description	Cancels a pending email change using the link sent to the current address
+//	@Tags			users
+//	@Produce		json
+//	@Param			token	path		string	true	"token"
+//	@Success		204		{string}	string	"Email change cancelled"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/v1/user/email/cancel/{token} [put]
+func (u *UserHandler) CancelEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := helper.ReadTokenParam(r)
+	if err != nil {
+		helper.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err = u.userService.CancelEmailChange(r.Context(), token); err != nil {
+		switch err {
+		case repository.ErrsNotFound:
+			helper.NotFoundResponse(w, r, err)
+		default:
+			helper.InternalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newHashedToken() (string, string) {
+	plainToken := uuid.New().String()
+	hash := sha256.Sum256([]byte(plainToken))
+	return plainToken, hex.EncodeToString(hash[:])
+}
+
 func GetUserFromContext(r *http.Request) *service_models.User {
 	user, _ := r.Context().Value(UserCTX).(*service_models.User)
 	return user
 }
 
-func NewUserHandler(userService service.UserService, followService service.FollowerService, cacheService service.CacheService) *UserHandler {
+func NewUserHandler(userService service.UserService, followService service.FollowerService, cacheService service.CacheService, mailService service.Mailer, sessionService service.SessionService, auditService service.AuditService) *UserHandler {
 	return &UserHandler{
 		userService:     userService,
 		followerService: followService,
 		cacheService:    cacheService,
+		mailService:     mailService,
+		sessionService:  sessionService,
+		auditService:    auditService,
 	}
 }
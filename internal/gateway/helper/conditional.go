@@ -0,0 +1,88 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag returns a strong entity tag for data, built from a digest of its JSON
+// form. A non-empty prefix, such as a version, is put in front so handlers can
+// compare it on its own.
+func ETag(prefix string, data any) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	digest := hex.EncodeToString(sum[:8])
+	if prefix != "" {
+		digest = prefix + "-" + digest
+	}
+	return `"` + digest + `"`, nil
+}
+
+// NotModified sets the ETag and Last-Modified headers and answers 304 when
+// the client's copy is current. If-None-Match takes precedence over
+// If-Modified-Since. A zero lastModified leaves Last-Modified out.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	notModified := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = matchesETag(inm, etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		notModified = err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// IfMatch reports whether the request may go ahead under its If-Match header.
+// A request without one always may. Otherwise match is called with each
+// listed strong tag, and "*" matches any current representation.
+func IfMatch(r *http.Request, match func(etag string) bool) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// If-Match uses the strong comparison, so weak tags never match.
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if match(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesETag looks for etag among the comma-separated tags of header, using
+// the weak comparison If-None-Match calls for.
+func matchesETag(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package helper
 
 import (
+	"fmt"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/json"
 	"github.com/saleh-ghazimoradi/Gophergram/logger"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func InternalServerError(w http.ResponseWriter, r *http.Request, err error) {
@@ -26,6 +29,11 @@ func ConflictResponse(w http.ResponseWriter, r *http.Request, err error) {
 	json.WriteJSONError(w, http.StatusConflict, err.Error())
 }
 
+func PreconditionFailedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	logger.Logger.Warn("precondition failed", "method", r.Method, "path", r.URL.Path, "err", err.Error())
+	json.WriteJSONError(w, http.StatusPreconditionFailed, err.Error())
+}
+
 func UnauthorizedErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	logger.Logger.Warn("unauthorized error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
 	json.WriteJSONError(w, http.StatusUnauthorized, "unauthorized")
@@ -47,3 +55,28 @@ func RateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfte
 	w.Header().Set("Retry_After", retryAfter)
 	json.WriteJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
 }
+
+func ActionLimitExceededResponse(w http.ResponseWriter, r *http.Request, err error, retryAfter time.Duration) {
+	logger.Logger.Warn("action limit exceeded", "method", r.Method, "path", r.URL.Path, "err", err.Error())
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	json.WriteJSONError(w, http.StatusTooManyRequests, err.Error())
+}
+
+func AccountSuspendedResponse(w http.ResponseWriter, r *http.Request, expiresAt *time.Time) {
+	logger.Logger.Warn("account suspended", "method", r.Method, "path", r.URL.Path)
+	message := "your account is suspended"
+	if expiresAt != nil {
+		message = fmt.Sprintf("your account is suspended until %s", expiresAt.Format(time.RFC3339))
+	}
+	json.WriteJSONError(w, http.StatusForbidden, message)
+}
+
+func ContentRejectedResponse(w http.ResponseWriter, r *http.Request, rule, reason string) {
+	logger.Logger.Warn("content rejected", "method", r.Method, "path", r.URL.Path, "rule", rule, "reason", reason)
+	type envelope struct {
+		Error  string `json:"error"`
+		Rule   string `json:"rule"`
+		Reason string `json:"reason"`
+	}
+	json.WriteJSON(w, http.StatusUnprocessableEntity, envelope{Error: "content rejected by policy", Rule: rule, Reason: reason})
+}
@@ -16,6 +16,23 @@ func ReadIdParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// ReadSessionIdParam reads the :session_id path segment.
+func ReadSessionIdParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := strconv.ParseInt(params.ByName("session_id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid session id")
+	}
+	return id, nil
+}
+
+// IsMeParam reports whether the :id path segment is "me". Routes that only
+// act on the current user share the /v1/users/:id prefix, since httprouter
+// cannot register /v1/users/me next to it.
+func IsMeParam(r *http.Request) bool {
+	return httprouter.ParamsFromContext(r.Context()).ByName("id") == "me"
+}
+
 func ReadTokenParam(r *http.Request) (string, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 	token := params.ByName("token")
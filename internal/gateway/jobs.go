@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/logger"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
+)
+
+func startRetentionJob(ctx context.Context, retention service.RetentionService) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(config.AppConfig.Retention.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				posts, comments, err := retention.Purge(ctx, config.AppConfig.Retention.Period)
+				if err != nil {
+					logger.Logger.Error("error purging deleted content", "error", err.Error())
+					continue
+				}
+				logger.Logger.Info("purged deleted content", "posts", posts, "comments", comments)
+			}
+		}
+	}()
+}
+
+// startStatsJob keeps the cached admin statistics fresh, computing them once
+// right away so the first request does not have to.
+func startStatsJob(ctx context.Context, stats service.StatsService) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(config.AppConfig.Stats.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := stats.Refresh(ctx); err != nil {
+				logger.Logger.Error("error refreshing stats", "error", err.Error())
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// startRedisMonitor pings Redis while it is unavailable so the app switches
+// back from degraded mode once it answers again.
+func startRedisMonitor(ctx context.Context, redis *utils.RedisClient) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(config.AppConfig.Redis.HealthInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !redis.Healthy() {
+					redis.Check(ctx)
+				}
+			}
+		}
+	}()
+}
+
+// startReplicaMonitor pings the read replicas so reads move off one that
+// stops answering and back once it recovers.
+func startReplicaMonitor(ctx context.Context, replicas *utils.ReadPool) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(config.AppConfig.DBConfig.ReplicaHealthInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				replicas.Check(ctx)
+			}
+		}
+	}()
+}
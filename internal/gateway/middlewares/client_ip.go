@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPResolver works out which address a request came from. The
+// forwarding headers are only read when the connection comes from a trusted
+// proxy. Their hops are then walked from the nearest one back, and the first
+// address that is not a trusted proxy is the client.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+}
+
+// Resolve prefers the standard Forwarded header, then X-Forwarded-For, then
+// X-Real-IP.
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	peer, ok := parseHop(remoteHost(r.RemoteAddr))
+	if !ok {
+		return remoteHost(r.RemoteAddr)
+	}
+	if !c.isTrusted(peer) {
+		return peer.String()
+	}
+
+	if hops := forwardedHops(r.Header.Values("Forwarded")); len(hops) > 0 {
+		return c.client(hops, peer).String()
+	}
+	if hops := forwardedForHops(r.Header.Values("X-Forwarded-For")); len(hops) > 0 {
+		return c.client(hops, peer).String()
+	}
+	if realIP, ok := parseHop(r.Header.Get("X-Real-IP")); ok {
+		return realIP.String()
+	}
+	return peer.String()
+}
+
+// client walks hops from the right. An entry that cannot be parsed ends the
+// walk at the last address known to be good.
+func (c *ClientIPResolver) client(hops []string, peer netip.Addr) netip.Addr {
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			return client
+		}
+		client = addr
+		if !c.isTrusted(addr) {
+			return client
+		}
+	}
+	return client
+}
+
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHops reads the for= parameters of RFC 7239 Forwarded headers.
+func forwardedHops(headers []string) []string {
+	var hops []string
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(name, "for") {
+					hops = append(hops, value)
+				}
+			}
+		}
+	}
+	return hops
+}
+
+func forwardedForHops(headers []string) []string {
+	var hops []string
+	for _, header := range headers {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	return hops
+}
+
+// parseHop reads one hop, which may be quoted, carry a port, or be an IPv6
+// address in brackets.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+	if strings.HasPrefix(hop, "[") {
+		end := strings.Index(hop, "]")
+		if end < 0 {
+			return netip.Addr{}, false
+		}
+		hop = hop[1:end]
+	} else if strings.Count(hop, ":") == 1 {
+		hop, _, _ = strings.Cut(hop, ":")
+	}
+
+	addr, err := netip.ParseAddr(hop)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// ClientIP stores the resolved client address in the request context, where
+// handlers.GetClientIPFromContext reads it.
+func (c *ClientIPResolver) ClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), handlers.ClientIPCTX, c.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// NewClientIPResolver accepts CIDRs and bare addresses.
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	resolver := &ClientIPResolver{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		resolver.trusted = append(resolver.trusted, prefix.Masked())
+	}
+	return resolver, nil
+}
@@ -6,25 +6,30 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/helper"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
-	"github.com/saleh-ghazimoradi/Gophergram/logger"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type CustomMiddleware struct {
 	postService      service.PostService
 	userService      service.UserService
 	authService      service.Authenticator
-	roleService      service.RoleService
+	policyService    service.PolicyService
 	cacheService     service.CacheService
 	rateLimitService service.RateLimitService
+	tokenService     service.PersonalAccessTokenService
+	sessionService   service.SessionService
+	readYourWrites   service.ReadYourWritesService
 }
 
 func (m *CustomMiddleware) PostsContextMiddleware(next http.Handler) http.Handler {
@@ -35,7 +40,7 @@ func (m *CustomMiddleware) PostsContextMiddleware(next http.Handler) http.Handle
 			return
 		}
 
-		post, err := m.postService.GetById(context.Background(), id)
+		post, err := m.postService.GetById(r.Context(), id)
 		if err != nil {
 			switch {
 			case errors.Is(err, repository.ErrsNotFound):
@@ -46,7 +51,7 @@ func (m *CustomMiddleware) PostsContextMiddleware(next http.Handler) http.Handle
 			return
 		}
 
-		ctx := context.WithValue(context.Background(), handlers.PostCtx, post)
+		ctx := context.WithValue(r.Context(), handlers.PostCtx, post)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -115,6 +120,11 @@ func (m *CustomMiddleware) AuthTokenMiddleware(next http.Handler) http.Handler {
 
 		token := parts[1]
 
+		if service.IsPersonalAccessToken(token) {
+			m.authenticatePersonalAccessToken(w, r, token, next)
+			return
+		}
+
 		jwtToken, err := m.authService.ValidateToken(token)
 		if err != nil {
 			helper.UnauthorizedErrorResponse(w, r, err)
@@ -128,14 +138,141 @@ func (m *CustomMiddleware) AuthTokenMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := m.getUser(context.Background(), userId)
+		ctx := m.readContext(r, userId)
+
+		user, err := m.getUser(ctx, userId)
 		if err != nil {
 			helper.UnauthorizedErrorResponse(w, r, err)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), handlers.UserCTX, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		if user.IsSuspended() {
+			helper.AccountSuspendedResponse(w, r, user.Suspension.ExpiresAt)
+			return
+		}
+
+		ctx = context.WithValue(ctx, handlers.UserCTX, user)
+
+		// Tokens from before sessions existed have no sid and cannot be
+		// revoked, so they are only accepted until the configured cutoff.
+		if sid, ok := claims["sid"]; ok {
+			sessionId, err := strconv.ParseInt(fmt.Sprintf("%.f", sid), 10, 64)
+			if err != nil {
+				helper.UnauthorizedErrorResponse(w, r, err)
+				return
+			}
+
+			session, err := m.sessionService.Validate(ctx, sessionId)
+			if err != nil || session.UserID != user.ID {
+				helper.UnauthorizedErrorResponse(w, r, repository.ErrSessionRevoked)
+				return
+			}
+
+			ctx = context.WithValue(ctx, handlers.SessionCTX, session)
+		} else if !time.Now().Before(config.AppConfig.Authentication.LegacyTokensCutoff) {
+			helper.UnauthorizedErrorResponse(w, r, repository.ErrSessionRequired)
+			return
+		}
+
+		m.serveMarkingWrites(w, r.WithContext(ctx), user.ID, next)
+	})
+}
+
+func (m *CustomMiddleware) authenticatePersonalAccessToken(w http.ResponseWriter, r *http.Request, plainToken string, next http.Handler) {
+	token, err := m.tokenService.Authenticate(r.Context(), plainToken)
+	if err != nil {
+		helper.UnauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	ctx := m.readContext(r, token.UserID)
+
+	user, err := m.getUser(ctx, token.UserID)
+	if err != nil {
+		helper.UnauthorizedErrorResponse(w, r, err)
+		return
+	}
+
+	if user.IsSuspended() {
+		helper.AccountSuspendedResponse(w, r, user.Suspension.ExpiresAt)
+		return
+	}
+
+	ctx = context.WithValue(ctx, handlers.UserCTX, user)
+	ctx = context.WithValue(ctx, handlers.TokenCTX, token)
+	m.serveMarkingWrites(w, r.WithContext(ctx), user.ID, next)
+}
+
+// readContext sends the reads of a user who wrote within the read-your-writes
+// window to the primary, as well as every read made by a request that may
+// write.
+func (m *CustomMiddleware) readContext(r *http.Request, userId int64) context.Context {
+	ctx := r.Context()
+	if isReadOnly(r) && !m.readYourWrites.RecentlyWrote(ctx, userId) {
+		return ctx
+	}
+	return utils.WithPrimary(ctx)
+}
+
+// serveMarkingWrites serves the request and starts a new read-your-writes
+// window when it was a write that succeeded. Failed writes changed nothing,
+// so they leave the user's reads on the replicas.
+func (m *CustomMiddleware) serveMarkingWrites(w http.ResponseWriter, r *http.Request, userId int64, next http.Handler) {
+	if isReadOnly(r) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+	if rec.status >= 200 && rec.status < 300 {
+		_ = m.readYourWrites.MarkWrite(r.Context(), userId)
+	}
+}
+
+func isReadOnly(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// RequireScope rejects requests authenticated with a personal access token
+// that was not granted the given scope. JWT sessions are not scope limited.
+func (m *CustomMiddleware) RequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := handlers.GetTokenFromContext(r)
+		if token != nil && !token.HasScope(scope) {
+			helper.ForbiddenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
@@ -152,34 +289,16 @@ func (m *CustomMiddleware) RecoverPanic(next http.Handler) http.Handler {
 }
 
 func (m *CustomMiddleware) getUser(ctx context.Context, id int64) (*service_models.User, error) {
-	logger.Logger.Info("cache hit", "key", "user", "id", id)
-	user, err := m.cacheService.Get(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	if user == nil {
-		logger.Logger.Info("fetching from DB", "id", id)
-		user, err = m.userService.GetById(ctx, id)
-		if err != nil {
-			return nil, err
-		}
-		if err = m.cacheService.Set(ctx, user); err != nil {
-			return nil, err
-		}
-	}
-	return user, nil
+	return m.cacheService.Fetch(ctx, id, func(ctx context.Context) (*service_models.User, error) {
+		return m.userService.GetById(ctx, id)
+	})
 }
-func (m *CustomMiddleware) CheckPostOwnership(requiredRole string, next http.Handler) http.Handler {
+func (m *CustomMiddleware) CheckPostOwnership(permission string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := handlers.GetUserFromContext(r)
 		post := handlers.GetPostFromCTX(r)
 
-		if post.UserID == user.ID {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		allowed, err := m.checkRolePrecedence(context.Background(), user, requiredRole)
+		allowed, err := m.policyService.CanActOn(r.Context(), user, post.UserID, permission)
 		if err != nil {
 			helper.InternalServerError(w, r, err)
 			return
@@ -194,31 +313,18 @@ func (m *CustomMiddleware) CheckPostOwnership(requiredRole string, next http.Han
 	})
 }
 
-func (m *CustomMiddleware) checkRolePrecedence(ctx context.Context, user *service_models.User, roleName string) (bool, error) {
-	role, err := m.roleService.GetByName(ctx, roleName)
-	if err != nil {
-		return false, err
-	}
-	return user.Role.Level >= role.Level, nil
-}
-
-func (m *CustomMiddleware) RateLimitMiddleware(next http.Handler) http.Handler {
+func (m *CustomMiddleware) RequirePermission(permission string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientIP := r.RemoteAddr
-		allowed, retryAfter, err := m.rateLimitService.IsAllowed(r.Context(), clientIP, config.AppConfig.Rate.Limit, config.AppConfig.Rate.Window)
+		user := handlers.GetUserFromContext(r)
 
-		if err != nil && errors.Is(err, repository.ErrRateLimitExceeded) {
-			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
-			helper.RateLimitExceededResponse(w, r, fmt.Sprintf("%v", config.AppConfig.Rate.Window))
-			return
-		} else if err != nil {
+		allowed, err := m.policyService.Can(r.Context(), user, permission)
+		if err != nil {
 			helper.InternalServerError(w, r, err)
 			return
 		}
 
 		if !allowed {
-			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
-			helper.RateLimitExceededResponse(w, r, fmt.Sprintf("%v", config.AppConfig.Rate.Window))
+			helper.ForbiddenResponse(w, r)
 			return
 		}
 
@@ -226,13 +332,108 @@ func (m *CustomMiddleware) RateLimitMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func NewMiddleware(postService service.PostService, userService service.UserService, authService service.Authenticator, roleService service.RoleService, cacheService service.CacheService, rateLimitService service.RateLimitService) *CustomMiddleware {
+// RateLimitMiddleware applies the default rate limit policy.
+func (m *CustomMiddleware) RateLimitMiddleware(next http.Handler) http.Handler {
+	return m.RateLimit(service_models.RateLimitPolicy{
+		Name:   "default",
+		Limit:  config.AppConfig.Rate.Limit,
+		Window: config.AppConfig.Rate.Window,
+	})(next)
+}
+
+// IPRateLimitMiddleware limits requests by address in front of
+// AuthTokenMiddleware, so requests with bad tokens cannot make it look up
+// tokens, sessions and users without limit. The per-user policy still
+// applies behind it.
+func (m *CustomMiddleware) IPRateLimitMiddleware(next http.Handler) http.Handler {
+	return m.RateLimit(service_models.RateLimitPolicy{
+		Name:   "ip",
+		Limit:  config.AppConfig.Rate.IPLimit,
+		Window: config.AppConfig.Rate.IPWindow,
+	})(next)
+}
+
+// AuthRateLimitMiddleware applies the stricter policy of the login,
+// registration and one-time token endpoints.
+func (m *CustomMiddleware) AuthRateLimitMiddleware(next http.Handler) http.Handler {
+	return m.RateLimit(service_models.RateLimitPolicy{
+		Name:   "auth",
+		Limit:  config.AppConfig.Rate.AuthLimit,
+		Window: config.AppConfig.Rate.AuthWindow,
+	})(next)
+}
+
+// TokenRateLimitMiddleware applies the policy for minting access tokens.
+func (m *CustomMiddleware) TokenRateLimitMiddleware(next http.Handler) http.Handler {
+	return m.RateLimit(service_models.RateLimitPolicy{
+		Name:   "token",
+		Limit:  config.AppConfig.Rate.TokenLimit,
+		Window: config.AppConfig.Rate.TokenWindow,
+	})(next)
+}
+
+// RateLimit limits requests under policy. Behind AuthTokenMiddleware clients
+// are counted by user, elsewhere by IP address. Every response carries the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+func (m *CustomMiddleware) RateLimit(policy service_models.RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientID := "ip:" + handlers.GetClientIPFromContext(r)
+			if user := handlers.GetUserFromContext(r); user != nil {
+				clientID = fmt.Sprintf("user:%d", user.ID)
+			}
+
+			result, err := m.rateLimitService.Allow(r.Context(), clientID, policy)
+			if err != nil {
+				helper.InternalServerError(w, r, err)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				helper.RateLimitExceededResponse(w, r, fmt.Sprintf("%v", time.Duration(retryAfter)*time.Second))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// RequestID tags every request with an id, reusing a sane X-Request-ID sent by
+// the client, so log lines and audit entries can be correlated.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-ID")
+		if requestId == "" || len(requestId) > 64 {
+			requestId = uuid.New().String()
+		}
+
+		w.Header().Set("X-Request-ID", requestId)
+		ctx := context.WithValue(r.Context(), handlers.RequestIDCTX, requestId)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func NewMiddleware(postService service.PostService, userService service.UserService, authService service.Authenticator, policyService service.PolicyService, cacheService service.CacheService, rateLimitService service.RateLimitService, tokenService service.PersonalAccessTokenService, sessionService service.SessionService, readYourWrites service.ReadYourWritesService) *CustomMiddleware {
 	return &CustomMiddleware{
 		postService:      postService,
 		userService:      userService,
 		authService:      authService,
-		roleService:      roleService,
+		policyService:    policyService,
 		cacheService:     cacheService,
 		rateLimitService: rateLimitService,
+		tokenService:     tokenService,
+		sessionService:   sessionService,
+		readYourWrites:   readYourWrites,
 	}
 }
@@ -3,50 +3,101 @@ package routes
 import (
 	"database/sql"
 	"fmt"
-	"github.com/go-redis/redis/v8"
 	"github.com/julienschmidt/httprouter"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 	"net/http"
+	"time"
 )
 
-func RegisterRoutes(router *httprouter.Router, db *sql.DB, client *redis.Client) {
-	health := handlers.NewHealthHandler()
+func RegisterRoutes(router *httprouter.Router, db *sql.DB, readDB utils.Querier, client *utils.RedisClient, cacheRepository repository.CacheRepository) {
+	health := handlers.NewHealthHandler(client)
 
-	userRepo := repository.NewUserRepository(db, db)
-	followRepo := repository.NewFollowerRepository(db, db)
-	postRepo := repository.NewPostRepository(db, db)
-	commentRepo := repository.NewCommentRepository(db, db)
-	roleRepo := repository.NewRoleRepository(db, db)
-	cacheRepository := repository.NewCacheRepository(client)
-	rateLimitRepository := repository.NewRateLimitRepository(client)
+	userRepo := repository.NewUserRepository(readDB, db)
+	followRepo := repository.NewFollowerRepository(db, readDB)
+	postRepo := repository.NewPostRepository(readDB, db)
+	commentRepo := repository.NewCommentRepository(readDB, db)
+	roleRepo := repository.NewRoleRepository(readDB, db)
+	rateLimitRepository := repository.NewRateLimitRepository(client, config.AppConfig.Rate.Algorithm)
+	tokenRepository := repository.NewPersonalAccessTokenRepository(readDB, db)
+	sessionRepository := repository.NewSessionRepository(readDB, db)
+	reportRepository := repository.NewReportRepository(readDB, db)
+	auditRepository := repository.NewAuditRepository(readDB, db)
+	suspensionRepository := repository.NewSuspensionRepository(readDB, db)
+	statsRepository := repository.NewStatsRepository(readDB, db)
+	timelineRepository := repository.NewTimelineRepository(client, config.AppConfig.Timeline.MaxLength, config.AppConfig.Timeline.TTL)
 
+	velocityService := service.NewVelocityService(rateLimitRepository, userRepo, service.VelocityPolicy{
+		NewAccountAge: config.AppConfig.Velocity.NewAccountAge,
+		Limits: map[service.VelocityAction]service.VelocityLimit{
+			service.VelocityFollow:  {Window: time.Hour, Limit: config.AppConfig.Velocity.FollowsPerHour, NewAccountLimit: config.AppConfig.Velocity.NewAccountFollowsPerHour},
+			service.VelocityPost:    {Window: 24 * time.Hour, Limit: config.AppConfig.Velocity.PostsPerDay, NewAccountLimit: config.AppConfig.Velocity.NewAccountPostsPerDay},
+			service.VelocityComment: {Window: time.Minute, Limit: config.AppConfig.Velocity.CommentsPerMinute, NewAccountLimit: config.AppConfig.Velocity.NewAccountCommentsPerMinute},
+		},
+	})
+
+	timelineService := service.NewTimelineService(timelineRepository, postRepo, followRepo, config.AppConfig.Timeline.MaxLength, config.AppConfig.Timeline.FanOutLimit)
 	userService := service.NewUserService(userRepo, db)
-	followService := service.NewFollowerService(followRepo)
-	postService := service.NewPostService(postRepo, db)
-	commentService := service.NewCommentService(commentRepo)
+	followService := service.NewFollowerService(followRepo, velocityService, timelineService, cacheRepository)
+	postService := service.NewPostService(postRepo, followRepo, commentRepo, velocityService, timelineService, cacheRepository, db)
+	commentService := service.NewCommentService(commentRepo, velocityService, cacheRepository)
 	mailService := service.NewMailer(config.AppConfig.Mail.ApiKey, config.AppConfig.Mail.FromEmail)
 	JWTAuthenticator := service.NewJWTAuthenticator(config.AppConfig.Authentication.Secret, config.AppConfig.Authentication.Aud, config.AppConfig.Authentication.Iss)
-	roleService := service.NewRoleService(roleRepo)
-	cacheService := service.NewCacheService(cacheRepository)
+	roleService := service.NewRoleService(roleRepo, userRepo, cacheRepository, db)
+	policyService := service.NewPolicyService(roleRepo)
+	cacheService := service.NewCacheService(cacheRepository, config.AppConfig.Cache.UserTTL)
 	rateLimitService := service.NewRateLimitService(rateLimitRepository)
+	tokenService := service.NewPersonalAccessTokenService(tokenRepository)
+	sessionService := service.NewSessionService(sessionRepository)
+	readYourWritesService := service.NewReadYourWritesService(cacheRepository, readYourWritesWindow())
+	reportService := service.NewReportService(reportRepository, postRepo, commentRepo, userRepo, suspensionRepository, policyService, cacheRepository, db)
+	auditService := service.NewAuditService(auditRepository)
+	suspensionService := service.NewSuspensionService(suspensionRepository, userRepo, cacheRepository)
+	statsService := service.NewStatsService(statsRepository, cacheRepository, config.AppConfig.Stats.Interval, config.AppConfig.Stats.SignupDays, config.AppConfig.Stats.TopTags)
+	contentPolicyService := service.NewContentPolicyService(reportRepository,
+		service.NewBannedWordsRule(config.AppConfig.ContentPolicy.BannedWords, config.AppConfig.ContentPolicy.BannedWordsAction),
+		service.NewLinkLimitRule(config.AppConfig.ContentPolicy.MaxPostLinks, config.AppConfig.ContentPolicy.MaxCommentLinks),
+		service.NewRepeatedContentRule(postRepo, commentRepo, config.AppConfig.ContentPolicy.RepeatWindow),
+	)
 
-	middleware := middlewares.NewMiddleware(postService, userService, JWTAuthenticator, roleService, cacheService, rateLimitService)
+	middleware := middlewares.NewMiddleware(postService, userService, JWTAuthenticator, policyService, cacheService, rateLimitService, tokenService, sessionService, readYourWritesService)
 
 	feedHandler := handlers.NewFeedHandler(postService)
-	userHandler := handlers.NewUserHandler(userService, followService, cacheService)
-	postHandler := handlers.NewPostHandler(postService, commentService)
-	authHandler := handlers.NewAuthHandler(userService, mailService, JWTAuthenticator)
+	userHandler := handlers.NewUserHandler(userService, followService, cacheService, mailService, sessionService, auditService)
+	postHandler := handlers.NewPostHandler(postService, commentService, auditService, contentPolicyService)
+	authHandler := handlers.NewAuthHandler(userService, mailService, JWTAuthenticator, sessionService, auditService, suspensionService)
+	tokenHandler := handlers.NewPersonalAccessTokenHandler(tokenService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	roleHandler := handlers.NewRoleHandler(roleService, auditService)
+	reportHandler := handlers.NewReportHandler(reportService, auditService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	suspensionHandler := handlers.NewSuspensionHandler(suspensionService, userService, policyService, auditService)
+	shadowBanHandler := handlers.NewShadowBanHandler(userService, auditService)
+	statsHandler := handlers.NewStatsHandler(statsService)
 
 	registerHealthRoutes(router, health, middleware)
 	registerUserRoutes(router, userHandler, middleware, feedHandler)
 	registerPostRoutes(router, postHandler, middleware)
 	registerAuthenticationRoutes(router, authHandler, middleware)
+	registerTokenRoutes(router, tokenHandler, middleware)
+	registerSessionRoutes(router, sessionHandler, middleware)
+	registerAdminRoutes(router, roleHandler, postHandler, auditHandler, suspensionHandler, shadowBanHandler, statsHandler, middleware)
+	registerReportRoutes(router, reportHandler, middleware)
 
 	docsURL := fmt.Sprintf("%s/swagger/doc.json", config.AppConfig.ServerConfig.Port)
 	router.Handler(http.MethodGet, "/swagger/*any", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 }
+
+// readYourWritesWindow is zero without replicas, since every read then goes to
+// the primary anyway.
+func readYourWritesWindow() time.Duration {
+	if len(config.AppConfig.DBConfig.ReplicaSources) == 0 {
+		return 0
+	}
+	return config.AppConfig.DBConfig.ReadYourWritesWindow
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+func registerAdminRoutes(router *httprouter.Router, role *handlers.RoleHandler, post *handlers.PostHandler, audit *handlers.AuditHandler, suspension *handlers.SuspensionHandler, shadowBan *handlers.ShadowBanHandler, stats *handlers.StatsHandler, middleware *middlewares.CustomMiddleware) {
+	authTokenMiddleware := middleware.AuthTokenMiddleware
+	ipRateLimit := middleware.IPRateLimitMiddleware
+	requireScope := middleware.RequireScope
+	requirePermission := middleware.RequirePermission
+	rateLimitMiddleware := middleware.RateLimitMiddleware
+	recoverPanic := middleware.RecoverPanic
+	commonHeader := middleware.CommonHeaders
+
+	admin := func(permission string, next http.HandlerFunc) http.Handler {
+		return commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeAdmin, requirePermission(permission, next)))))))
+	}
+
+	router.Handler(http.MethodGet, "/v1/admin/roles", admin(service_models.PermissionRolesManage, role.GetRolesHandler))
+	router.Handler(http.MethodPost, "/v1/admin/roles", admin(service_models.PermissionRolesManage, role.CreateRoleHandler))
+	router.Handler(http.MethodPatch, "/v1/admin/roles/:id", admin(service_models.PermissionRolesManage, role.UpdateRoleHandler))
+	router.Handler(http.MethodPut, "/v1/admin/users/:id/role", admin(service_models.PermissionRolesManage, role.AssignRoleHandler))
+	router.Handler(http.MethodDelete, "/v1/admin/users/:id/role", admin(service_models.PermissionRolesManage, role.RevokeRoleHandler))
+	router.Handler(http.MethodPut, "/v1/admin/users/:id/suspension", admin(service_models.PermissionUsersSuspend, suspension.SuspendUserHandler))
+	router.Handler(http.MethodDelete, "/v1/admin/users/:id/suspension", admin(service_models.PermissionUsersSuspend, suspension.LiftSuspensionHandler))
+	router.Handler(http.MethodGet, "/v1/admin/users/:id/shadow-ban", admin(service_models.PermissionUsersShadowBan, shadowBan.GetShadowBanHandler))
+	router.Handler(http.MethodPut, "/v1/admin/users/:id/shadow-ban", admin(service_models.PermissionUsersShadowBan, shadowBan.ShadowBanUserHandler))
+	router.Handler(http.MethodDelete, "/v1/admin/users/:id/shadow-ban", admin(service_models.PermissionUsersShadowBan, shadowBan.LiftShadowBanHandler))
+	router.Handler(http.MethodPut, "/v1/admin/posts/:id/restore", admin(service_models.PermissionContentRestore, post.RestorePostHandler))
+	router.Handler(http.MethodPut, "/v1/admin/comments/:id/restore", admin(service_models.PermissionContentRestore, post.RestoreCommentHandler))
+	router.Handler(http.MethodGet, "/v1/admin/audit", admin(service_models.PermissionAuditRead, audit.GetAuditLogsHandler))
+	router.Handler(http.MethodGet, "/v1/admin/stats", admin(service_models.PermissionStatsRead, stats.GetStatsHandler))
+}
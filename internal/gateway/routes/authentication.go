@@ -8,9 +8,9 @@ import (
 )
 
 func registerAuthenticationRoutes(router *httprouter.Router, authHandler *handlers.AuthHandler, middleware *middlewares.CustomMiddleware) {
-	rateLimitMiddleware := middleware.RateLimitMiddleware
+	authRateLimit := middleware.AuthRateLimitMiddleware
 	recoverPanic := middleware.RecoverPanic
 	commonHeader := middleware.CommonHeaders
-	router.Handler(http.MethodPost, "/v1/authentication/user", commonHeader(recoverPanic(rateLimitMiddleware(http.HandlerFunc(authHandler.RegisterUserHandler)))))
-	router.Handler(http.MethodPost, "/v1/authentication/token", commonHeader(recoverPanic(rateLimitMiddleware(http.HandlerFunc(authHandler.CreateTokenHandler)))))
+	router.Handler(http.MethodPost, "/v1/authentication/user", commonHeader(recoverPanic(authRateLimit(http.HandlerFunc(authHandler.RegisterUserHandler)))))
+	router.Handler(http.MethodPost, "/v1/authentication/token", commonHeader(recoverPanic(authRateLimit(http.HandlerFunc(authHandler.CreateTokenHandler)))))
 }
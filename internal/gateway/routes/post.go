@@ -4,19 +4,24 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
 	"net/http"
 )
 
 func registerPostRoutes(router *httprouter.Router, handler *handlers.PostHandler, middleware *middlewares.CustomMiddleware) {
 	authTokenMiddleware := middleware.AuthTokenMiddleware
+	ipRateLimit := middleware.IPRateLimitMiddleware
+	requireScope := middleware.RequireScope
 	postMiddleware := middleware.PostsContextMiddleware
 	checkOwnership := middleware.CheckPostOwnership
 	rateLimitMiddleware := middleware.RateLimitMiddleware
 	recoverPanic := middleware.RecoverPanic
 	commonHeader := middleware.CommonHeaders
 
-	router.Handler(http.MethodPost, "/v1/posts", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(http.HandlerFunc(handler.CreatePostHandler))))))
-	router.Handler(http.MethodGet, "/v1/posts/:id", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(postMiddleware(http.HandlerFunc(handler.GetPostByIdHandler)))))))
-	router.Handler(http.MethodPatch, "/v1/posts/:id", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(postMiddleware(checkOwnership("moderator", http.HandlerFunc(handler.UpdatePostHandler))))))))
-	router.Handler(http.MethodDelete, "/v1/posts/:id", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(postMiddleware(checkOwnership("admin", http.HandlerFunc(handler.DeletePostHandler))))))))
+	router.Handler(http.MethodPost, "/v1/posts", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopePostsWrite, http.HandlerFunc(handler.CreatePostHandler))))))))
+	router.Handler(http.MethodGet, "/v1/posts/:id", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopePostsRead, postMiddleware(http.HandlerFunc(handler.GetPostByIdHandler)))))))))
+	router.Handler(http.MethodPatch, "/v1/posts/:id", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopePostsWrite, postMiddleware(checkOwnership(service_models.PermissionPostsUpdateAny, http.HandlerFunc(handler.UpdatePostHandler))))))))))
+	router.Handler(http.MethodGet, "/v1/posts/:id/revisions", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopePostsRead, postMiddleware(http.HandlerFunc(handler.GetPostRevisionsHandler)))))))))
+	router.Handler(http.MethodPost, "/v1/posts/:id/comments", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopePostsWrite, postMiddleware(http.HandlerFunc(handler.CreateCommentHandler)))))))))
+	router.Handler(http.MethodDelete, "/v1/posts/:id", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopePostsWrite, postMiddleware(checkOwnership(service_models.PermissionPostsDeleteAny, http.HandlerFunc(handler.DeletePostHandler))))))))))
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+func registerReportRoutes(router *httprouter.Router, handler *handlers.ReportHandler, middleware *middlewares.CustomMiddleware) {
+	authTokenMiddleware := middleware.AuthTokenMiddleware
+	ipRateLimit := middleware.IPRateLimitMiddleware
+	requireScope := middleware.RequireScope
+	requirePermission := middleware.RequirePermission
+	rateLimitMiddleware := middleware.RateLimitMiddleware
+	recoverPanic := middleware.RecoverPanic
+	commonHeader := middleware.CommonHeaders
+
+	moderator := func(next http.HandlerFunc) http.Handler {
+		return commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeAdmin, requirePermission(service_models.PermissionReportsReview, next)))))))
+	}
+
+	router.Handler(http.MethodPost, "/v1/reports", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeUsersWrite, http.HandlerFunc(handler.CreateReportHandler))))))))
+	router.Handler(http.MethodGet, "/v1/moderation/reports", moderator(handler.GetReportQueueHandler))
+	router.Handler(http.MethodPost, "/v1/moderation/reports/resolve", moderator(handler.ResolveReportsHandler))
+}
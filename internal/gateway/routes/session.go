@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+func registerSessionRoutes(router *httprouter.Router, handler *handlers.SessionHandler, middleware *middlewares.CustomMiddleware) {
+	authTokenMiddleware := middleware.AuthTokenMiddleware
+	ipRateLimit := middleware.IPRateLimitMiddleware
+	requireScope := middleware.RequireScope
+	rateLimitMiddleware := middleware.RateLimitMiddleware
+	recoverPanic := middleware.RecoverPanic
+	commonHeader := middleware.CommonHeaders
+
+	router.Handler(http.MethodGet, "/v1/users/:id/sessions", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeSessionsRead, http.HandlerFunc(handler.GetSessionsHandler))))))))
+	router.Handler(http.MethodDelete, "/v1/users/:id/sessions", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeSessionsWrite, http.HandlerFunc(handler.RevokeOtherSessionsHandler))))))))
+	router.Handler(http.MethodDelete, "/v1/users/:id/sessions/:session_id", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeSessionsWrite, http.HandlerFunc(handler.RevokeSessionHandler))))))))
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/julienschmidt/httprouter"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"net/http"
+)
+
+func registerTokenRoutes(router *httprouter.Router, handler *handlers.PersonalAccessTokenHandler, middleware *middlewares.CustomMiddleware) {
+	authTokenMiddleware := middleware.AuthTokenMiddleware
+	ipRateLimit := middleware.IPRateLimitMiddleware
+	requireScope := middleware.RequireScope
+	rateLimitMiddleware := middleware.RateLimitMiddleware
+	tokenRateLimit := middleware.TokenRateLimitMiddleware
+	recoverPanic := middleware.RecoverPanic
+	commonHeader := middleware.CommonHeaders
+
+	router.Handler(http.MethodPost, "/v1/user/tokens", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(tokenRateLimit(requireScope(service_models.ScopeTokensWrite, http.HandlerFunc(handler.CreatePersonalAccessTokenHandler))))))))
+	router.Handler(http.MethodGet, "/v1/user/tokens", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeTokensRead, http.HandlerFunc(handler.GetPersonalAccessTokensHandler))))))))
+	router.Handler(http.MethodDelete, "/v1/user/tokens/:id", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeTokensWrite, http.HandlerFunc(handler.DeletePersonalAccessTokenHandler))))))))
+}
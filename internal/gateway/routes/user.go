@@ -4,17 +4,25 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/handlers"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
 	"net/http"
 )
 
 func registerUserRoutes(router *httprouter.Router, user *handlers.UserHandler, middleware *middlewares.CustomMiddleware, feed *handlers.FeedHandler) {
 	authTokenMiddleware := middleware.AuthTokenMiddleware
+	ipRateLimit := middleware.IPRateLimitMiddleware
+	requireScope := middleware.RequireScope
 	rateLimitMiddleware := middleware.RateLimitMiddleware
+	authRateLimit := middleware.AuthRateLimitMiddleware
 	recoverPanic := middleware.RecoverPanic
 	commonHeader := middleware.CommonHeaders
-	router.Handler(http.MethodPut, "/v1/user/activate/:token", commonHeader(recoverPanic(rateLimitMiddleware(http.HandlerFunc(user.ActivateUserHandler)))))
-	router.Handler(http.MethodGet, "/v1/users/:id", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(http.HandlerFunc(user.GetUserHandler))))))
-	router.Handler(http.MethodPut, "/v1/users/:id/follow", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(http.HandlerFunc(user.FollowUserHandler))))))
-	router.Handler(http.MethodPut, "/v1/users/:id/unfollow", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(http.HandlerFunc(user.UnFollowUserHandler))))))
-	router.Handler(http.MethodGet, "/v1/user/feed", commonHeader(recoverPanic(rateLimitMiddleware(authTokenMiddleware(http.HandlerFunc(feed.GetUserFeedHandler))))))
+	router.Handler(http.MethodPut, "/v1/user/activate/:token", commonHeader(recoverPanic(authRateLimit(http.HandlerFunc(user.ActivateUserHandler)))))
+	router.Handler(http.MethodGet, "/v1/users/:id", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeUsersRead, http.HandlerFunc(user.GetUserHandler))))))))
+	router.Handler(http.MethodPut, "/v1/users/:id/follow", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeUsersWrite, http.HandlerFunc(user.FollowUserHandler))))))))
+	router.Handler(http.MethodPut, "/v1/users/:id/unfollow", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeUsersWrite, http.HandlerFunc(user.UnFollowUserHandler))))))))
+	router.Handler(http.MethodPost, "/v1/user/email", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeAccountWrite, http.HandlerFunc(user.ChangeEmailHandler))))))))
+	router.Handler(http.MethodPut, "/v1/user/password", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeAccountWrite, http.HandlerFunc(user.ChangePasswordHandler))))))))
+	router.Handler(http.MethodPut, "/v1/user/email/confirm/:token", commonHeader(recoverPanic(authRateLimit(http.HandlerFunc(user.ConfirmEmailChangeHandler)))))
+	router.Handler(http.MethodPut, "/v1/user/email/cancel/:token", commonHeader(recoverPanic(authRateLimit(http.HandlerFunc(user.CancelEmailChangeHandler)))))
+	router.Handler(http.MethodGet, "/v1/user/feed", commonHeader(recoverPanic(ipRateLimit(authTokenMiddleware(rateLimitMiddleware(requireScope(service_models.ScopeFeedRead, http.HandlerFunc(feed.GetUserFeedHandler))))))))
 }
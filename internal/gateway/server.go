@@ -6,7 +6,10 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/docs"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/middlewares"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/gateway/routes"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service"
 	"github.com/saleh-ghazimoradi/Gophergram/logger"
 	"github.com/saleh-ghazimoradi/Gophergram/utils"
 	"net/http"
@@ -28,17 +31,48 @@ func Server() error {
 		return err
 	}
 
-	redis, err := utils.RedisConnection(config.AppConfig.Redis.Addr, config.AppConfig.Redis.PW, config.AppConfig.Redis.DB)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	replicas, err := utils.NewReadPool(db, config.AppConfig.DBConfig.ReplicaSources)
 	if err != nil {
-		logger.Logger.Error(err.Error())
+		return err
+	}
+	defer replicas.Close()
+	if len(config.AppConfig.DBConfig.ReplicaSources) > 0 {
+		startReplicaMonitor(jobsCtx, replicas)
 	}
 
+	var redis *utils.RedisClient
+	if config.AppConfig.Redis.Enabled {
+		redis, err = utils.RedisConnection(config.AppConfig.Redis.Addr, config.AppConfig.Redis.PW, config.AppConfig.Redis.DB)
+		if err != nil {
+			logger.Logger.Warn("starting without redis, running degraded", "err", err.Error())
+		}
+		startRedisMonitor(jobsCtx, redis)
+	} else {
+		logger.Logger.Info("redis is disabled")
+	}
+
+	clientIP, err := middlewares.NewClientIPResolver(config.AppConfig.ServerConfig.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	cacheRepository := newCacheRepository(redis)
+
 	router := httprouter.New()
-	routes.RegisterRoutes(router, db, redis)
+	routes.RegisterRoutes(router, db, replicas, redis, cacheRepository)
+
+	retention := service.NewRetentionService(repository.NewPostRepository(db, db), repository.NewCommentRepository(db, db))
+	startRetentionJob(jobsCtx, retention)
+
+	stats := service.NewStatsService(repository.NewStatsRepository(replicas, db), cacheRepository, config.AppConfig.Stats.Interval, config.AppConfig.Stats.SignupDays, config.AppConfig.Stats.TopTags)
+	startStatsJob(jobsCtx, stats)
 
 	srv := &http.Server{
 		Addr:         config.AppConfig.ServerConfig.Port,
-		Handler:      router,
+		Handler:      middlewares.RequestID(clientIP.ClientIP(router)),
 		ReadTimeout:  config.AppConfig.ServerConfig.ReadTimeout,
 		WriteTimeout: config.AppConfig.ServerConfig.WriteTimeout,
 		IdleTimeout:  config.AppConfig.ServerConfig.IdleTimeout,
@@ -62,6 +96,7 @@ func Server() error {
 
 		logger.Logger.Info("completing background tasks", "addr", srv.Addr)
 
+		stopJobs()
 		wg.Wait()
 		shutdownError <- nil
 	}()
@@ -82,3 +117,13 @@ func Server() error {
 
 	return nil
 }
+
+// newCacheRepository picks the configured cache backend. The memory backend
+// is shared by the routes and the background jobs, so it is built once here.
+func newCacheRepository(redis *utils.RedisClient) repository.CacheRepository {
+	if config.AppConfig.Cache.Backend == config.CacheBackendMemory {
+		logger.Logger.Info("caching in memory", "max_entries", config.AppConfig.Cache.MaxEntries)
+		return repository.NewMemoryCacheRepository(config.AppConfig.Cache.MaxEntries)
+	}
+	return repository.NewCacheRepository(redis)
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, log *service_models.AuditLog) error
+	GetAll(ctx context.Context, q service_models.AuditQuery) ([]service_models.AuditLog, error)
+	// CountLoginFailure adds a failed login to the user's running count and
+	// returns the new count.
+	CountLoginFailure(ctx context.Context, userId int64, ip string) (int, error)
+	// ClearLoginFailures resets the user's count and returns what it was.
+	ClearLoginFailures(ctx context.Context, userId int64) (int, error)
+	WithTx(tx *sql.Tx) AuditRepository
+}
+
+type auditRepository struct {
+	dbRead  utils.Querier
+	dbWrite *sql.DB
+	tx      *sql.Tx
+}
+
+func (a *auditRepository) Create(ctx context.Context, log *service_models.AuditLog) error {
+	query := `
+		INSERT INTO audit_logs (actor_id, action, target_type, target_id, before, after, ip, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{log.ActorID, log.Action, log.TargetType, log.TargetID, nullJSON(log.Before), nullJSON(log.After), log.IP, log.RequestID}
+	return querier(a.tx, a.dbWrite).QueryRowContext(ctx, query, args...).Scan(&log.ID, &log.CreatedAt)
+}
+
+func (a *auditRepository) GetAll(ctx context.Context, q service_models.AuditQuery) ([]service_models.AuditLog, error) {
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, before, after, ip, request_id, created_at
+		FROM audit_logs
+		WHERE ($1 = 0 OR actor_id = $1)
+		AND ($2 = '' OR action = $2)
+		AND ($3 = '' OR target_type = $3)
+		AND ($4 = 0 OR target_id = $4)
+		AND ($5::timestamptz IS NULL OR created_at >= $5)
+		AND ($6::timestamptz IS NULL OR created_at <= $6)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $7 OFFSET $8
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{q.ActorID, q.Action, q.TargetType, q.TargetID, nullTime(q.Since), nullTime(q.Until), q.Limit, q.Offset}
+	rows, err := querier(a.tx, a.dbRead).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := make([]service_models.AuditLog, 0)
+	for rows.Next() {
+		var log service_models.AuditLog
+		var before, after []byte
+		err = rows.Scan(
+			&log.ID,
+			&log.ActorID,
+			&log.Action,
+			&log.TargetType,
+			&log.TargetID,
+			&before,
+			&after,
+			&log.IP,
+			&log.RequestID,
+			&log.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		log.Before = before
+		log.After = after
+		logs = append(logs, log)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (a *auditRepository) WithTx(tx *sql.Tx) AuditRepository {
+	return &auditRepository{
+		dbRead:  a.dbRead,
+		dbWrite: a.dbWrite,
+		tx:      tx,
+	}
+}
+
+func (a *auditRepository) CountLoginFailure(ctx context.Context, userId int64, ip string) (int, error) {
+	query := `
+		INSERT INTO login_failures (user_id, last_ip) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET attempts = login_failures.attempts + 1, last_failed_at = NOW(), last_ip = EXCLUDED.last_ip
+		RETURNING attempts
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var attempts int
+	err := querier(a.tx, a.dbWrite).QueryRowContext(ctx, query, userId, ip).Scan(&attempts)
+	return attempts, err
+}
+
+func (a *auditRepository) ClearLoginFailures(ctx context.Context, userId int64) (int, error) {
+	query := `DELETE FROM login_failures WHERE user_id = $1 RETURNING attempts`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var attempts int
+	err := querier(a.tx, a.dbWrite).QueryRowContext(ctx, query, userId).Scan(&attempts)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	return attempts, nil
+}
+
+func nullJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func NewAuditRepository(dbRead utils.Querier, dbWrite *sql.DB) AuditRepository {
+	return &auditRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
@@ -2,55 +2,126 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/go-redis/redis/v8"
-	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 	"time"
 )
 
-const UserExpiration = time.Minute
-
+// CacheRepository stores encoded values under string keys. A missing key
+// reads as nil. While Redis is unavailable every lookup misses and writes are
+// skipped, so callers fall through to the database. Deletes are skipped too,
+// so the Redis cache is emptied before it is used again. Set ignores values
+// with a ttl of zero or less, so a zero TTL setting turns that cache off.
 type CacheRepository interface {
-	Get(ctx context.Context, id int64) (*service_models.User, error)
-	Set(ctx context.Context, user *service_models.User) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+func UserCacheKey(id int64) string {
+	return fmt.Sprintf("user-%d", id)
+}
+
+func PostCacheKey(id int64) string {
+	return fmt.Sprintf("post-%d", id)
+}
+
+func CommentCountCacheKey(postId int64) string {
+	return fmt.Sprintf("comment-count-%d", postId)
 }
 
+// FeedCacheKey names the cached first page of a user's feed.
+func FeedCacheKey(userId int64) string {
+	return fmt.Sprintf("feed-%d", userId)
+}
+
+// ReadYourWritesCacheKey marks a user who wrote recently.
+func ReadYourWritesCacheKey(userId int64) string {
+	return fmt.Sprintf("recent-write-%d", userId)
+}
+
+const StatsCacheKey = "admin-stats"
+
+// cachePatterns match every key the cache stores values under.
+var cachePatterns = []string{"user-*", "post-*", "comment-count-*", "feed-*", StatsCacheKey}
+
+// cacheFlushBatch is how many keys one SCAN step returns and one DEL removes.
+const cacheFlushBatch = 1000
+
 type cacheRepository struct {
-	client *redis.Client
+	client *utils.RedisClient
 }
 
-func (c *cacheRepository) Get(ctx context.Context, id int64) (*service_models.User, error) {
-	cacheKey := fmt.Sprintf("user-%v", id)
-	data, err := c.client.Get(ctx, cacheKey).Result()
+func (c *cacheRepository) Get(ctx context.Context, key string) ([]byte, error) {
+	if !c.client.Healthy() {
+		return nil, nil
+	}
+
+	data, err := c.client.Get(ctx, key).Bytes()
 	if err == redis.Nil {
 		return nil, nil
 	} else if err != nil {
-		return nil, err
+		c.client.MarkDown(err)
+		return nil, nil
 	}
+	return data, nil
+}
 
-	user := &service_models.User{}
-	if data != "" {
-		err := json.Unmarshal([]byte(data), &user)
-		if err != nil {
-			return nil, err
-		}
+func (c *cacheRepository) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if !c.client.Healthy() || ttl <= 0 {
+		return nil
+	}
+
+	if err := c.client.SetEX(ctx, key, value, ttl).Err(); err != nil {
+		c.client.MarkDown(err)
+	}
+	return nil
+}
+
+func (c *cacheRepository) Delete(ctx context.Context, keys ...string) error {
+	if !c.client.Healthy() || len(keys) == 0 {
+		return nil
 	}
 
-	return user, nil
+	if err := c.client.Del(ctx, keys...).Err(); err != nil {
+		c.client.MarkDown(err)
+	}
+	return nil
 }
 
-func (c *cacheRepository) Set(ctx context.Context, user *service_models.User) error {
-	cacheKey := fmt.Sprintf("user-%v", user.ID)
-	data, err := json.Marshal(user)
-	if err != nil {
-		return err
+// flush drops every cached value. It runs when Redis comes back, since the
+// invalidations skipped while it was down would otherwise leave entries
+// written before the outage to be served until they expire.
+func (c *cacheRepository) flush(ctx context.Context) error {
+	for _, pattern := range cachePatterns {
+		iter := c.client.Scan(ctx, 0, pattern, cacheFlushBatch).Iterator()
+		keys := make([]string, 0, cacheFlushBatch)
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+			if len(keys) == cacheFlushBatch {
+				if err := c.client.Del(ctx, keys...).Err(); err != nil {
+					return err
+				}
+				keys = keys[:0]
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := c.client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
 	}
-	return c.client.SetEX(ctx, cacheKey, data, UserExpiration).Err()
+	return nil
 }
 
-func NewCacheRepository(client *redis.Client) CacheRepository {
-	return &cacheRepository{
+func NewCacheRepository(client *utils.RedisClient) CacheRepository {
+	cache := &cacheRepository{
 		client: client,
 	}
+	client.OnRecover(cache.flush)
+	return cache
 }
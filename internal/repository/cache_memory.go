@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCacheEntry is one cached value with its expiry.
+type memoryCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// memoryCacheRepository keeps cached values in process memory. Once it holds
+// maxEntries values, the least recently used one is evicted to make room.
+// Expired values read as missing and are dropped when they are next touched.
+type memoryCacheRepository struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+	now        func() time.Time
+}
+
+func (m *memoryCacheRepository) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	entry := element.Value.(*memoryCacheEntry)
+	if !m.now().Before(entry.expiresAt) {
+		m.remove(element)
+		return nil, nil
+	}
+
+	m.order.MoveToFront(element)
+	return entry.value, nil
+}
+
+func (m *memoryCacheRepository) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 || m.maxEntries <= 0 {
+		return nil
+	}
+
+	// Callers may reuse value after Set returns.
+	stored := make([]byte, len(value))
+	copy(stored, value)
+	expiresAt := m.now().Add(ttl)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		entry := element.Value.(*memoryCacheEntry)
+		entry.value = stored
+		entry.expiresAt = expiresAt
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryCacheEntry{key: key, value: stored, expiresAt: expiresAt})
+	for m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *memoryCacheRepository) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := m.entries[key]; ok {
+			m.remove(element)
+		}
+	}
+	return nil
+}
+
+func (m *memoryCacheRepository) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*memoryCacheEntry).key)
+}
+
+func NewMemoryCacheRepository(maxEntries int) CacheRepository {
+	return &memoryCacheRepository{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
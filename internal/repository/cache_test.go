@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// cacheBackends builds each CacheRepository implementation for the shared
+// suite, along with a function that moves its clock forward.
+var cacheBackends = []struct {
+	name string
+	new  func(t *testing.T) (CacheRepository, func(time.Duration))
+}{
+	{
+		name: "redis",
+		new: func(t *testing.T) (CacheRepository, func(time.Duration)) {
+			server, client := newTestRedis(t)
+			return NewCacheRepository(client), server.FastForward
+		},
+	},
+	{
+		name: "memory",
+		new: func(t *testing.T) (CacheRepository, func(time.Duration)) {
+			cache := NewMemoryCacheRepository(100).(*memoryCacheRepository)
+			now := time.Now()
+			cache.now = func() time.Time { return now }
+			return cache, func(d time.Duration) { now = now.Add(d) }
+		},
+	},
+}
+
+func TestCacheRepository(t *testing.T) {
+	for _, backend := range cacheBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			t.Run("missing key reads as nil", func(t *testing.T) {
+				cache, _ := backend.new(t)
+				assertCached(t, cache, "user-1", nil)
+			})
+
+			t.Run("set then get", func(t *testing.T) {
+				cache, _ := backend.new(t)
+				mustSet(t, cache, "user-1", []byte("first"), time.Minute)
+				assertCached(t, cache, "user-1", []byte("first"))
+
+				mustSet(t, cache, "user-1", []byte("second"), time.Minute)
+				assertCached(t, cache, "user-1", []byte("second"))
+			})
+
+			t.Run("value is copied on set", func(t *testing.T) {
+				cache, _ := backend.new(t)
+				value := []byte("first")
+				mustSet(t, cache, "user-1", value, time.Minute)
+				copy(value, "XXXXX")
+				assertCached(t, cache, "user-1", []byte("first"))
+			})
+
+			t.Run("entries expire after their ttl", func(t *testing.T) {
+				cache, advance := backend.new(t)
+				mustSet(t, cache, "user-1", []byte("first"), time.Minute)
+
+				advance(time.Minute - time.Second)
+				assertCached(t, cache, "user-1", []byte("first"))
+
+				advance(time.Second)
+				assertCached(t, cache, "user-1", nil)
+			})
+
+			t.Run("overwriting renews the ttl", func(t *testing.T) {
+				cache, advance := backend.new(t)
+				mustSet(t, cache, "user-1", []byte("first"), time.Minute)
+				advance(30 * time.Second)
+				mustSet(t, cache, "user-1", []byte("second"), time.Minute)
+
+				advance(45 * time.Second)
+				assertCached(t, cache, "user-1", []byte("second"))
+			})
+
+			t.Run("non-positive ttl is not stored", func(t *testing.T) {
+				cache, _ := backend.new(t)
+				for _, ttl := range []time.Duration{0, -time.Second} {
+					mustSet(t, cache, "user-1", []byte("first"), ttl)
+					assertCached(t, cache, "user-1", nil)
+				}
+
+				// The skipped write must not have taken the backend out of
+				// service.
+				mustSet(t, cache, "user-1", []byte("first"), time.Minute)
+				assertCached(t, cache, "user-1", []byte("first"))
+			})
+
+			t.Run("delete removes every key given", func(t *testing.T) {
+				cache, _ := backend.new(t)
+				for i := 1; i <= 3; i++ {
+					mustSet(t, cache, UserCacheKey(int64(i)), []byte("user"), time.Minute)
+				}
+
+				if err := cache.Delete(context.Background(), UserCacheKey(1), UserCacheKey(2), "missing"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				assertCached(t, cache, UserCacheKey(1), nil)
+				assertCached(t, cache, UserCacheKey(2), nil)
+				assertCached(t, cache, UserCacheKey(3), []byte("user"))
+			})
+
+			t.Run("delete without keys", func(t *testing.T) {
+				cache, _ := backend.new(t)
+				if err := cache.Delete(context.Background()); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+			})
+		})
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCacheRepository(2)
+
+	mustSet(t, cache, "user-1", []byte("1"), time.Minute)
+	mustSet(t, cache, "user-2", []byte("2"), time.Minute)
+	assertCached(t, cache, "user-1", []byte("1"))
+	mustSet(t, cache, "user-3", []byte("3"), time.Minute)
+
+	assertCached(t, cache, "user-2", nil)
+	assertCached(t, cache, "user-1", []byte("1"))
+	assertCached(t, cache, "user-3", []byte("3"))
+}
+
+func TestRedisCacheSkippedWhileDown(t *testing.T) {
+	cache := NewCacheRepository(newDownRedis(t))
+	ctx := context.Background()
+
+	mustSet(t, cache, "user-1", []byte("first"), time.Minute)
+	assertCached(t, cache, "user-1", nil)
+	if err := cache.Delete(ctx, "user-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+}
+
+func mustSet(t *testing.T, cache CacheRepository, key string, value []byte, ttl time.Duration) {
+	t.Helper()
+
+	if err := cache.Set(context.Background(), key, value, ttl); err != nil {
+		t.Fatalf("Set(%s, ttl %v): %v", key, ttl, err)
+	}
+}
+
+func assertCached(t *testing.T, cache CacheRepository, key string, want []byte) {
+	t.Helper()
+
+	got, err := cache.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", key, err)
+	}
+	if !bytes.Equal(got, want) || (got == nil) != (want == nil) {
+		t.Fatalf("Get(%s) = %q, want %q", key, got, want)
+	}
+}
+
+func TestRedisCacheFlushedOnRecovery(t *testing.T) {
+	server, client := newTestRedis(t)
+	cache := NewCacheRepository(client)
+	ctx := context.Background()
+
+	key := UserCacheKey(1)
+	if err := cache.Set(ctx, key, []byte("before the outage"), time.Hour); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := server.Set("ratelimit:gcra:user:1", "1"); err != nil {
+		t.Fatalf("seeding another namespace: %v", err)
+	}
+
+	// The invalidation is skipped while Redis is marked down.
+	client.MarkDown(errors.New("connection refused"))
+	if err := cache.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if !server.Exists(key) {
+		t.Fatal("Delete reached Redis while it was marked down")
+	}
+
+	if !client.Check(ctx) {
+		t.Fatal("Check did not bring Redis back")
+	}
+	if server.Exists(key) {
+		t.Fatal("entry written before the outage survived recovery")
+	}
+	if !server.Exists("ratelimit:gcra:user:1") {
+		t.Fatal("recovery flushed keys outside the cache")
+	}
+}
@@ -3,29 +3,41 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
 )
 
 type CommentRepository interface {
-	GetByPostId(ctx context.Context, id int64) ([]service_models.Comment, error)
+	GetByPostId(ctx context.Context, id, viewerId int64) ([]service_models.Comment, error)
+	GetById(ctx context.Context, id int64) (*service_models.Comment, error)
 	Create(ctx context.Context, comment *service_models.Comment) error
+	Hide(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) (int64, error)
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	CountRecentByContent(ctx context.Context, userId, excludeId int64, content string, since time.Time) (int, error)
+	CountByPostId(ctx context.Context, postId int64) (int, error)
 	WithTx(tx *sql.Tx) CommentRepository
 }
 
 type commentRepository struct {
-	dbRead  *sql.DB
+	dbRead  utils.Querier
 	dbWrite *sql.DB
 	tx      *sql.Tx
 }
 
-func (c *commentRepository) GetByPostId(ctx context.Context, id int64) ([]service_models.Comment, error) {
-	query := `SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id FROM comments c JOIN users on users.id = c.user_id WHERE c.post_id = $1 ORDER BY c.created_at DESC;`
+// GetByPostId lists the comments on a post as seen by viewerId, who is the
+// only one to see their own comments while shadow-banned.
+func (c *commentRepository) GetByPostId(ctx context.Context, id, viewerId int64) ([]service_models.Comment, error) {
+	query := `SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id FROM comments c JOIN users on users.id = c.user_id JOIN posts p ON p.id = c.post_id WHERE c.post_id = $1 AND c.hidden_at IS NULL AND c.deleted_at IS NULL AND p.deleted_at IS NULL AND (users.shadow_banned = false OR c.user_id = $2) ORDER BY c.created_at DESC;`
 
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
-	rows, err := c.dbRead.QueryContext(ctx, query, id)
+	rows, err := querier(c.tx, c.dbRead).QueryContext(ctx, query, id, viewerId)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +71,7 @@ func (c *commentRepository) Create(ctx context.Context, comment *service_models.
 	query := `INSERT INTO comments (post_id, user_id, content) VALUES ($1, $2, $3) RETURNING id, created_at`
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
-	err := c.dbWrite.QueryRowContext(
+	err := querier(c.tx, c.dbWrite).QueryRowContext(
 		ctx,
 		query,
 		comment.PostID,
@@ -75,6 +87,134 @@ func (c *commentRepository) Create(ctx context.Context, comment *service_models.
 	return nil
 }
 
+func (c *commentRepository) GetById(ctx context.Context, id int64) (*service_models.Comment, error) {
+	query := `SELECT id, post_id, user_id, content, created_at FROM comments WHERE id = $1 AND hidden_at IS NULL AND deleted_at IS NULL`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	comment := &service_models.Comment{}
+	err := querier(c.tx, c.dbRead).QueryRowContext(ctx, query, id).Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.Content,
+		&comment.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+func (c *commentRepository) Hide(ctx context.Context, id int64) error {
+	query := `UPDATE comments SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(c.tx, c.dbWrite).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (c *commentRepository) Delete(ctx context.Context, id int64) error {
+	query := `UPDATE comments SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(c.tx, c.dbWrite).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+// Restore undeletes a comment and returns the id of its post. A comment that
+// is also hidden stays hidden, so its post is returned here rather than looked
+// up afterwards.
+func (c *commentRepository) Restore(ctx context.Context, id int64) (int64, error) {
+	query := `UPDATE comments SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING post_id`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var postId int64
+	if err := querier(c.tx, c.dbWrite).QueryRowContext(ctx, query, id).Scan(&postId); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrsNotFound
+		default:
+			return 0, err
+		}
+	}
+	return postId, nil
+}
+
+func (c *commentRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := `DELETE FROM comments WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(c.tx, c.dbWrite).ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (c *commentRepository) CountRecentByContent(ctx context.Context, userId, excludeId int64, content string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM comments WHERE user_id = $1 AND id <> $2 AND content = $3 AND created_at > $4 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var count int
+	if err := querier(c.tx, c.dbRead).QueryRowContext(ctx, query, userId, excludeId, content, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountByPostId counts the comments everyone can see on a post, leaving out
+// those of shadow-banned users.
+func (c *commentRepository) CountByPostId(ctx context.Context, postId int64) (int, error) {
+	query := `SELECT COUNT(*) FROM comments c JOIN users u ON u.id = c.user_id WHERE c.post_id = $1 AND c.hidden_at IS NULL AND c.deleted_at IS NULL AND u.shadow_banned = false`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var count int
+	if err := querier(c.tx, c.dbRead).QueryRowContext(ctx, query, postId).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (c *commentRepository) WithTx(tx *sql.Tx) CommentRepository {
 	return &commentRepository{
 		dbRead:  c.dbRead,
@@ -83,7 +223,7 @@ func (c *commentRepository) WithTx(tx *sql.Tx) CommentRepository {
 	}
 }
 
-func NewCommentRepository(dbRead, dbWrite *sql.DB) CommentRepository {
+func NewCommentRepository(dbRead utils.Querier, dbWrite *sql.DB) CommentRepository {
 	return &commentRepository{
 		dbRead:  dbRead,
 		dbWrite: dbWrite,
@@ -8,4 +8,12 @@ var (
 	ErrDuplicateUsername = errors.New("duplicate username")
 	ErrDuplicateEmail    = errors.New("duplicate email")
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+	ErrSessionRevoked    = errors.New("session has been revoked")
+	ErrSessionRequired   = errors.New("token has no session, sign in again")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrInvalidAction     = errors.New("action does not apply to this target")
+	ErrInvalidPassword   = errors.New("invalid password")
+	ErrEditConflict      = errors.New("the resource was changed by someone else")
+	ErrSuspendSelf       = errors.New("you cannot suspend yourself")
+	ErrOutranked         = errors.New("the account outranks yours")
 )
@@ -5,17 +5,20 @@ import (
 	"database/sql"
 	"github.com/lib/pq"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 )
 
 type FollowerRepository interface {
 	Follow(ctx context.Context, followerId, userId int64) error
 	Unfollow(ctx context.Context, followerId, userId int64) error
+	GetFollowerIds(ctx context.Context, userId int64) ([]int64, error)
+	CountFollowers(ctx context.Context, userId int64) (int, error)
 	WithTx(tx *sql.Tx) FollowerRepository
 }
 
 type followerRepository struct {
 	dbWrite *sql.DB
-	dbRead  *sql.DB
+	dbRead  utils.Querier
 	tx      *sql.Tx
 }
 
@@ -24,7 +27,7 @@ func (f *followerRepository) Follow(ctx context.Context, followerId, userId int6
 
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
-	_, err := f.dbWrite.ExecContext(ctx, query, followerId, userId)
+	_, err := querier(f.tx, f.dbWrite).ExecContext(ctx, query, followerId, userId)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			return ErrsConflict
@@ -38,11 +41,51 @@ func (f *followerRepository) Unfollow(ctx context.Context, followerId, userId in
 
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
-	_, err := f.dbWrite.ExecContext(ctx, query, followerId, userId)
+	_, err := querier(f.tx, f.dbWrite).ExecContext(ctx, query, followerId, userId)
 
 	return err
 }
 
+// GetFollowerIds lists the users who follow userId.
+func (f *followerRepository) GetFollowerIds(ctx context.Context, userId int64) ([]int64, error) {
+	query := `SELECT user_id FROM followers WHERE follower_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(f.tx, f.dbRead).QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (f *followerRepository) CountFollowers(ctx context.Context, userId int64) (int, error) {
+	query := `SELECT COUNT(*) FROM followers WHERE follower_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var count int
+	if err := querier(f.tx, f.dbRead).QueryRowContext(ctx, query, userId).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (f *followerRepository) WithTx(tx *sql.Tx) FollowerRepository {
 	return &followerRepository{
 		dbWrite: f.dbWrite,
@@ -51,7 +94,7 @@ func (f *followerRepository) WithTx(tx *sql.Tx) FollowerRepository {
 	}
 }
 
-func NewFollowerRepository(dbWrite *sql.DB, dbRead *sql.DB) FollowerRepository {
+func NewFollowerRepository(dbWrite *sql.DB, dbRead utils.Querier) FollowerRepository {
 	return &followerRepository{
 		dbWrite: dbWrite,
 		dbRead:  dbRead,
@@ -8,19 +8,33 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
 )
 
 type PostRepository interface {
 	Create(ctx context.Context, post *service_models.Post) error
 	GetById(ctx context.Context, id int64) (*service_models.Post, error)
 	GetUserFeed(ctx context.Context, id int64, fq service_models.PaginatedFeedQuery) ([]service_models.PostFeed, error)
+	GetFeedByIds(ctx context.Context, viewerId int64, ids []int64) ([]service_models.PostFeed, error)
+	GetVisibleIds(ctx context.Context, viewerId int64, ids []int64) ([]int64, error)
+	GetTimelineEntries(ctx context.Context, userId int64, limit int) ([]service_models.TimelineEntry, error)
+	GetEntriesByUser(ctx context.Context, userId int64, limit int) ([]service_models.TimelineEntry, error)
+	GetFanOutOnReadEntries(ctx context.Context, viewerId int64, minFollowers, limit int) ([]service_models.TimelineEntry, error)
 	Delete(ctx context.Context, id int64) error
 	Update(ctx context.Context, post *service_models.Post) error
+	Hide(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	CountRecentByContent(ctx context.Context, userId, excludeId int64, content string, since time.Time) (int, error)
+	CreateRevision(ctx context.Context, revision *service_models.PostRevision) error
+	GetRevisions(ctx context.Context, postId int64) ([]service_models.PostRevision, error)
+	GetRevision(ctx context.Context, postId int64, version int) (*service_models.PostRevision, error)
 	WithTx(tx *sql.Tx) PostRepository
 }
 
 type postRepository struct {
-	dbRead  *sql.DB
+	dbRead  utils.Querier
 	dbWrite *sql.DB
 	tx      *sql.Tx
 }
@@ -33,21 +47,21 @@ func (p *postRepository) Create(ctx context.Context, post *service_models.Post)
 
 	args := []any{post.Content, post.Title, post.UserID, pq.Array(post.Tags)}
 
-	if err := p.dbWrite.QueryRowContext(ctx, query, args...).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt); err != nil {
+	if err := querier(p.tx, p.dbWrite).QueryRowContext(ctx, query, args...).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (p *postRepository) GetById(ctx context.Context, id int64) (*service_models.Post, error) {
-	query := `SELECT id, content, title, user_id, tags, created_at, updated_at , version FROM posts WHERE id = $1`
+	query := `SELECT id, content, title, user_id, tags, created_at, updated_at , version FROM posts WHERE id = $1 AND hidden_at IS NULL AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
 	var post service_models.Post
 
-	err := p.dbRead.QueryRowContext(ctx, query, id).Scan(&post.ID, &post.Content, &post.Title, &post.UserID, pq.Array(&post.Tags), &post.CreatedAt, &post.UpdatedAt, &post.Version)
+	err := querier(p.tx, p.dbRead).QueryRowContext(ctx, query, id).Scan(&post.ID, &post.Content, &post.Title, &post.UserID, pq.Array(&post.Tags), &post.CreatedAt, &post.UpdatedAt, &post.Version)
 
 	if err != nil {
 		switch {
@@ -57,16 +71,17 @@ func (p *postRepository) GetById(ctx context.Context, id int64) (*service_models
 			return nil, err
 		}
 	}
+	post.Edited = post.Version > 0
 	return &post, nil
 }
 
 func (p *postRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM posts WHERE id = $1`
+	query := `UPDATE posts SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
-	result, err := p.dbWrite.ExecContext(ctx, query, id)
+	result, err := querier(p.tx, p.dbWrite).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -82,34 +97,123 @@ func (p *postRepository) Delete(ctx context.Context, id int64) error {
 }
 
 func (p *postRepository) Update(ctx context.Context, post *service_models.Post) error {
-	query := `UPDATE posts SET title = $1, content = $2, version = version + 1 WHERE id = $3 AND version = $4 RETURNING version`
+	query := `UPDATE posts SET title = $1, content = $2, version = version + 1, updated_at = NOW() WHERE id = $3 AND version = $4 AND deleted_at IS NULL RETURNING version, updated_at`
 
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
-	err := p.dbWrite.QueryRowContext(ctx, query, post.Title, post.Content, post.ID, post.Version).Scan(&post.Version)
+	err := querier(p.tx, p.dbWrite).QueryRowContext(ctx, query, post.Title, post.Content, post.ID, post.Version).Scan(&post.Version, &post.UpdatedAt)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return ErrsNotFound
+			return p.updateMissed(ctx, post.ID)
 		default:
 			return err
 		}
 	}
+	post.Edited = true
 	return nil
 }
 
+// updateMissed tells why Update matched no row: the post is gone, or it is
+// at another version than the one being edited.
+func (p *postRepository) updateMissed(ctx context.Context, id int64) error {
+	query := `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL)`
+
+	var exists bool
+	if err := querier(p.tx, p.dbWrite).QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrsNotFound
+	}
+	return ErrEditConflict
+}
+
+func (p *postRepository) Hide(ctx context.Context, id int64) error {
+	query := `UPDATE posts SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(p.tx, p.dbWrite).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (p *postRepository) Restore(ctx context.Context, id int64) error {
+	query := `UPDATE posts SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(p.tx, p.dbWrite).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+// Purge permanently removes posts soft-deleted before deletedBefore together
+// with their comments.
+func (p *postRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	query := `
+		WITH purged AS (
+			DELETE FROM posts WHERE deleted_at < $1 RETURNING id
+		), purged_comments AS (
+			DELETE FROM comments WHERE post_id IN (SELECT id FROM purged)
+		)
+		SELECT COUNT(*) FROM purged
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var count int64
+	if err := querier(p.tx, p.dbWrite).QueryRowContext(ctx, query, deletedBefore).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetUserFeed lists posts by the user and the accounts they follow. Content of
+// shadow-banned users is left out unless it is the user's own. Comment counts
+// are left to the caller; only ViewerComments is filled in.
 func (p *postRepository) GetUserFeed(ctx context.Context, id int64, fq service_models.PaginatedFeedQuery) ([]service_models.PostFeed, error) {
 	query := `
 	   SELECT
 	       p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
 	       u.username,
-	       COUNT(c.id) AS comments_count
+	       (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id
+	           WHERE c.post_id = p.id AND c.user_id = $1 AND cu.shadow_banned
+	           AND c.hidden_at IS NULL AND c.deleted_at IS NULL) AS viewer_comments
 	   FROM posts p
-	   LEFT JOIN comments c ON c.post_id = p.id
 	   LEFT JOIN users u ON p.user_id = u.id
 	   JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
-	   WHERE f.user_id = $1 OR p.user_id = $1
+	   WHERE (f.user_id = $1 OR p.user_id = $1) AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+	       AND (u.shadow_banned = false OR p.user_id = $1)
+	       AND ($4::text = '' OR p.title ILIKE '%' || $4::text || '%' OR p.content ILIKE '%' || $4::text || '%')
+	       AND (cardinality($5::varchar[]) = 0 OR p.tags @> $5::varchar[])
+	       AND ($6::timestamptz IS NULL OR p.created_at >= $6)
+	       AND ($7::timestamptz IS NULL OR p.created_at <= $7)
 	   GROUP BY p.id, u.username
 	   ORDER BY p.created_at ` + fq.Sort + `
 	   LIMIT $2 OFFSET $3
@@ -118,12 +222,102 @@ func (p *postRepository) GetUserFeed(ctx context.Context, id int64, fq service_m
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
-	rows, err := p.dbRead.QueryContext(ctx, query, id, fq.Limit, fq.Offset)
+	args := []any{id, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags), nullTime(fq.Since), nullTime(fq.Until)}
+	rows, err := querier(p.tx, p.dbRead).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanFeed(rows)
+}
+
+// GetFeedByIds loads the given posts the way GetUserFeed shows them, in the
+// order of ids. Posts the viewer may not see are left out.
+func (p *postRepository) GetFeedByIds(ctx context.Context, viewerId int64, ids []int64) ([]service_models.PostFeed, error) {
+	query := `
+	   SELECT
+	       p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
+	       u.username,
+	       (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id
+	           WHERE c.post_id = p.id AND c.user_id = $1 AND cu.shadow_banned
+	           AND c.hidden_at IS NULL AND c.deleted_at IS NULL) AS viewer_comments
+	   FROM posts p
+	   JOIN users u ON p.user_id = u.id
+	   WHERE p.id = ANY($2) AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+	       AND (u.shadow_banned = false OR p.user_id = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(p.tx, p.dbRead).QueryContext(ctx, query, viewerId, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts, err := scanFeed(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int64]service_models.PostFeed, len(posts))
+	for _, post := range posts {
+		byId[post.ID] = post
+	}
+
+	feed := make([]service_models.PostFeed, 0, len(posts))
+	for _, id := range ids {
+		if post, ok := byId[id]; ok {
+			feed = append(feed, post)
+		}
+	}
+	return feed, nil
+}
+
+// GetVisibleIds keeps the ids of the posts GetFeedByIds would show the
+// viewer, in the order given.
+func (p *postRepository) GetVisibleIds(ctx context.Context, viewerId int64, ids []int64) ([]int64, error) {
+	query := `
+	   SELECT p.id
+	   FROM posts p
+	   JOIN users u ON p.user_id = u.id
+	   WHERE p.id = ANY($2) AND p.hidden_at IS NULL AND p.deleted_at IS NULL
+	       AND (u.shadow_banned = false OR p.user_id = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(p.tx, p.dbRead).QueryContext(ctx, query, viewerId, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	visible := make(map[int64]bool, len(ids))
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		visible[id] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	kept := make([]int64, 0, len(visible))
+	for _, id := range ids {
+		if visible[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+func scanFeed(rows *sql.Rows) ([]service_models.PostFeed, error) {
 	var feed []service_models.PostFeed
 	for rows.Next() {
 		var ps service_models.PostFeed
@@ -136,14 +330,165 @@ func (p *postRepository) GetUserFeed(ctx context.Context, id int64, fq service_m
 			&ps.Version,
 			pq.Array(&ps.Tags),
 			&ps.User.Username,
-			&ps.CommentCount,
+			&ps.ViewerComments,
 		)
 		if err != nil {
 			return nil, err
 		}
+		ps.Edited = ps.Version > 0
 		feed = append(feed, ps)
 	}
-	return feed, nil
+	return feed, rows.Err()
+}
+
+// GetTimelineEntries lists the newest posts by the user and the accounts they
+// follow, for building their timeline.
+func (p *postRepository) GetTimelineEntries(ctx context.Context, userId int64, limit int) ([]service_models.TimelineEntry, error) {
+	query := `
+	   SELECT id, created_at FROM posts
+	   WHERE (user_id = $1 OR user_id IN (SELECT follower_id FROM followers WHERE user_id = $1))
+	       AND hidden_at IS NULL AND deleted_at IS NULL
+	   ORDER BY created_at DESC
+	   LIMIT $2
+	`
+	return p.queryTimelineEntries(ctx, query, userId, limit)
+}
+
+// GetEntriesByUser lists the newest posts by one user.
+func (p *postRepository) GetEntriesByUser(ctx context.Context, userId int64, limit int) ([]service_models.TimelineEntry, error) {
+	query := `
+	   SELECT id, created_at FROM posts
+	   WHERE user_id = $1 AND hidden_at IS NULL AND deleted_at IS NULL
+	   ORDER BY created_at DESC
+	   LIMIT $2
+	`
+	return p.queryTimelineEntries(ctx, query, userId, limit)
+}
+
+// GetFanOutOnReadEntries lists the newest posts by the accounts the viewer
+// follows that have at least minFollowers followers. Those posts are not
+// copied into timelines.
+func (p *postRepository) GetFanOutOnReadEntries(ctx context.Context, viewerId int64, minFollowers, limit int) ([]service_models.TimelineEntry, error) {
+	query := `
+	   SELECT id, created_at FROM posts
+	   WHERE user_id IN (
+	       SELECT f.follower_id FROM followers f
+	       WHERE f.user_id = $1
+	           AND (SELECT COUNT(*) FROM followers c WHERE c.follower_id = f.follower_id) >= $2
+	   ) AND hidden_at IS NULL AND deleted_at IS NULL
+	   ORDER BY created_at DESC
+	   LIMIT $3
+	`
+	return p.queryTimelineEntries(ctx, query, viewerId, minFollowers, limit)
+}
+
+func (p *postRepository) queryTimelineEntries(ctx context.Context, query string, args ...any) ([]service_models.TimelineEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(p.tx, p.dbRead).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]service_models.TimelineEntry, 0)
+	for rows.Next() {
+		var entry service_models.TimelineEntry
+		if err = rows.Scan(&entry.PostID, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (p *postRepository) CountRecentByContent(ctx context.Context, userId, excludeId int64, content string, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM posts WHERE user_id = $1 AND id <> $2 AND content = $3 AND created_at > $4 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var count int
+	if err := querier(p.tx, p.dbRead).QueryRowContext(ctx, query, userId, excludeId, content, since).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (p *postRepository) CreateRevision(ctx context.Context, revision *service_models.PostRevision) error {
+	query := `INSERT INTO post_revisions (post_id, version, title, content, edited_by) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{revision.PostID, revision.Version, revision.Title, revision.Content, revision.EditedBy}
+	if err := querier(p.tx, p.dbWrite).QueryRowContext(ctx, query, args...).Scan(&revision.ID, &revision.CreatedAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *postRepository) GetRevisions(ctx context.Context, postId int64) ([]service_models.PostRevision, error) {
+	query := `SELECT id, post_id, version, title, content, edited_by, created_at FROM post_revisions WHERE post_id = $1 ORDER BY version DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(p.tx, p.dbRead).QueryContext(ctx, query, postId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]service_models.PostRevision, 0)
+	for rows.Next() {
+		var revision service_models.PostRevision
+		err = rows.Scan(
+			&revision.ID,
+			&revision.PostID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Content,
+			&revision.EditedBy,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (p *postRepository) GetRevision(ctx context.Context, postId int64, version int) (*service_models.PostRevision, error) {
+	query := `SELECT id, post_id, version, title, content, edited_by, created_at FROM post_revisions WHERE post_id = $1 AND version = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	revision := &service_models.PostRevision{}
+	err := querier(p.tx, p.dbRead).QueryRowContext(ctx, query, postId, version).Scan(
+		&revision.ID,
+		&revision.PostID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Content,
+		&revision.EditedBy,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return revision, nil
 }
 
 func (p *postRepository) WithTx(tx *sql.Tx) PostRepository {
@@ -154,7 +499,7 @@ func (p *postRepository) WithTx(tx *sql.Tx) PostRepository {
 	}
 }
 
-func NewPostRepository(dbRead, dbWrite *sql.DB) PostRepository {
+func NewPostRepository(dbRead utils.Querier, dbWrite *sql.DB) PostRepository {
 	return &postRepository{
 		dbRead:  dbRead,
 		dbWrite: dbWrite,
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+)
+
+// querier returns the handle a repository statement runs on: the caller's
+// transaction for a repository returned by WithTx, otherwise db. Reads made
+// inside a transaction therefore see its uncommitted writes.
+func querier(tx *sql.Tx, db utils.Querier) utils.Querier {
+	if tx != nil {
+		return tx
+	}
+	return db
+}
@@ -2,34 +2,152 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"sync/atomic"
 	"time"
 )
 
+// RateLimitRepository admits or rejects one hit against key. In Redis each
+// algorithm runs as a single Lua script, so the check and the update happen
+// atomically and every key it writes carries an expiry.
 type RateLimitRepository interface {
-	IncrementRequestCount(ctx context.Context, clientID string, window time.Duration) (int64, error)
-	SetExpiration(ctx context.Context, clientID string, window time.Duration) error
-	GetTTL(ctx context.Context, clientID string) (time.Duration, error)
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*service_models.RateLimitResult, error)
 }
 
+// All scripts take the current time and the window in milliseconds, which
+// keeps the values within the precision Lua passes on to Redis, and reply
+// with {allowed, remaining, retry_after_ms, reset_ms}.
+var (
+	// fixedWindowScript counts hits in a window that starts with the first hit.
+	fixedWindowScript = redis.NewScript(`
+		local window = tonumber(ARGV[2])
+		local limit = tonumber(ARGV[3])
+		local count = redis.call('INCR', KEYS[1])
+		local ttl = redis.call('PTTL', KEYS[1])
+		if ttl < 0 then
+			ttl = window
+			redis.call('PEXPIRE', KEYS[1], ttl)
+		end
+		if count > limit then
+			return {0, 0, ttl, ttl}
+		end
+		return {1, limit - count, 0, ttl}
+	`)
+
+	// slidingWindowScript keeps a log of the hits of the last window in a
+	// sorted set scored by time.
+	slidingWindowScript = redis.NewScript(`
+		local now = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local limit = tonumber(ARGV[3])
+		redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+		local count = redis.call('ZCARD', KEYS[1])
+		if count >= limit then
+			local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+			local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+			return {0, 0, tonumber(oldest[2]) + window - now, tonumber(newest[2]) + window - now}
+		end
+		redis.call('ZADD', KEYS[1], ARGV[1], ARGV[4])
+		redis.call('PEXPIRE', KEYS[1], window)
+		return {1, limit - count - 1, 0, window}
+	`)
+
+	// gcraScript implements the generic cell rate algorithm: it stores the
+	// theoretical arrival time of the next hit and admits a burst of up to
+	// limit hits spread over window.
+	gcraScript = redis.NewScript(`
+		local now = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local limit = tonumber(ARGV[3])
+		local interval = window / limit
+		local tat = tonumber(redis.call('GET', KEYS[1]))
+		if not tat or tat < now then
+			tat = now
+		end
+		local new_tat = tat + interval
+		local allow_at = new_tat - window
+		if now < allow_at then
+			return {0, 0, math.ceil(allow_at - now), math.ceil(tat - now)}
+		end
+		redis.call('SET', KEYS[1], string.format('%.3f', new_tat), 'PX', math.ceil(new_tat - now))
+		return {1, math.floor((now - allow_at) / interval), 0, math.ceil(new_tat - now)}
+	`)
+)
+
 type rateLimitRepository struct {
-	client *redis.Client
+	client    *utils.RedisClient
+	algorithm string
+	script    *redis.Script
+	sequence  uint64
+	local     RateLimitRepository
+	now       func() time.Time
 }
 
-func (r *rateLimitRepository) IncrementRequestCount(ctx context.Context, clientID string, window time.Duration) (int64, error) {
-	return r.client.Incr(ctx, clientID).Result()
-}
+func (r *rateLimitRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (*service_models.RateLimitResult, error) {
+	if !r.client.Healthy() {
+		return r.local.Allow(ctx, key, limit, window)
+	}
 
-func (r *rateLimitRepository) SetExpiration(ctx context.Context, clientID string, window time.Duration) error {
-	return r.client.Expire(ctx, clientID, window).Err()
+	result, err := r.allowInRedis(ctx, key, limit, window)
+	if err != nil {
+		r.client.MarkDown(err)
+		return r.local.Allow(ctx, key, limit, window)
+	}
+	return result, nil
 }
 
-func (r *rateLimitRepository) GetTTL(ctx context.Context, clientID string) (time.Duration, error) {
-	return r.client.TTL(ctx, clientID).Result()
+func (r *rateLimitRepository) allowInRedis(ctx context.Context, key string, limit int, window time.Duration) (*service_models.RateLimitResult, error) {
+	if limit <= 0 {
+		return &service_models.RateLimitResult{Limit: limit, RetryAfter: window, Reset: window}, nil
+	}
+
+	now := r.now().UnixMilli()
+	// The sliding window log needs a unique member per hit.
+	member := fmt.Sprintf("%d-%d", now, atomic.AddUint64(&r.sequence, 1))
+
+	keys := []string{fmt.Sprintf("ratelimit:%s:%s", r.algorithm, key)}
+	reply, err := r.script.Run(ctx, r.client, keys, now, window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+
+	return &service_models.RateLimitResult{
+		Allowed:    reply[0] == 1,
+		Limit:      limit,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+		Reset:      time.Duration(reply[3]) * time.Millisecond,
+	}, nil
 }
 
-func NewRateLimitRepository(client *redis.Client) RateLimitRepository {
+// NewRateLimitRepository returns a limiter for one of the config.RateAlgorithm
+// values, defaulting to the sliding window. While Redis is unavailable it
+// counts in process instead, so each instance then enforces the limits on its
+// own.
+func NewRateLimitRepository(client *utils.RedisClient, algorithm string) RateLimitRepository {
+	var script *redis.Script
+	switch algorithm {
+	case config.RateAlgorithmFixedWindow:
+		script = fixedWindowScript
+	case config.RateAlgorithmGCRA:
+		script = gcraScript
+	default:
+		algorithm = config.RateAlgorithmSlidingWindow
+		script = slidingWindowScript
+	}
+
 	return &rateLimitRepository{
-		client: client,
+		client:    client,
+		algorithm: algorithm,
+		script:    script,
+		local:     NewLocalRateLimitRepository(),
+		now:       time.Now,
 	}
 }
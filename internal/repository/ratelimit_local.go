@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"sync"
+	"time"
+)
+
+// localSweepInterval is how often expired keys are dropped from memory.
+const localSweepInterval = time.Minute
+
+// localRateLimitRepository runs GCRA in process memory. It backs the Redis
+// limiter while Redis is unavailable.
+type localRateLimitRepository struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+func (l *localRateLimitRepository) Allow(ctx context.Context, key string, limit int, window time.Duration) (*service_models.RateLimitResult, error) {
+	if limit <= 0 {
+		return &service_models.RateLimitResult{Limit: limit, RetryAfter: window, Reset: window}, nil
+	}
+
+	now := time.Now()
+	interval := max(window/time.Duration(limit), 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	tat, ok := l.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(interval)
+	allowAt := newTat.Add(-window)
+	if now.Before(allowAt) {
+		return &service_models.RateLimitResult{
+			Limit:      limit,
+			RetryAfter: allowAt.Sub(now),
+			Reset:      tat.Sub(now),
+		}, nil
+	}
+
+	l.tats[key] = newTat
+	return &service_models.RateLimitResult{
+		Allowed:   true,
+		Limit:     limit,
+		Remaining: int(now.Sub(allowAt) / interval),
+		Reset:     newTat.Sub(now),
+	}, nil
+}
+
+func (l *localRateLimitRepository) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < localSweepInterval {
+		return
+	}
+	for key, tat := range l.tats {
+		if tat.Before(now) {
+			delete(l.tats, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func NewLocalRateLimitRepository() RateLimitRepository {
+	return &localRateLimitRepository{
+		tats:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+)
+
+// newTestRateLimiter returns a Redis limiter whose clock only moves when the
+// test advances it. advance also moves miniredis time, so expiries follow.
+func newTestRateLimiter(t *testing.T, algorithm string) (RateLimitRepository, *miniredis.Miniredis, func(time.Duration)) {
+	t.Helper()
+
+	server, client := newTestRedis(t)
+	limiter := NewRateLimitRepository(client, algorithm).(*rateLimitRepository)
+
+	now := time.UnixMilli(1_700_000_000_000)
+	limiter.now = func() time.Time { return now }
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		server.FastForward(d)
+	}
+	return limiter, server, advance
+}
+
+func allow(t *testing.T, limiter RateLimitRepository, limit int, window time.Duration) *service_models.RateLimitResult {
+	t.Helper()
+
+	result, err := limiter.Allow(context.Background(), "user:1", limit, window)
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	return result
+}
+
+func assertResult(t *testing.T, got *service_models.RateLimitResult, want service_models.RateLimitResult) {
+	t.Helper()
+
+	if *got != want {
+		t.Fatalf("got %+v, want %+v", *got, want)
+	}
+}
+
+func assertExpiry(t *testing.T, server *miniredis.Miniredis, key string, want time.Duration) {
+	t.Helper()
+
+	if ttl := server.TTL(key); ttl != want {
+		t.Fatalf("TTL of %s = %v, want %v", key, ttl, want)
+	}
+}
+
+func TestFixedWindowRateLimit(t *testing.T) {
+	limiter, server, advance := newTestRateLimiter(t, config.RateAlgorithmFixedWindow)
+	key := "ratelimit:fixed_window:user:1"
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		assertResult(t, allow(t, limiter, 3, time.Minute), service_models.RateLimitResult{
+			Allowed: true, Limit: 3, Remaining: remaining, Reset: time.Minute,
+		})
+	}
+	assertExpiry(t, server, key, time.Minute)
+
+	advance(20 * time.Second)
+	assertResult(t, allow(t, limiter, 3, time.Minute), service_models.RateLimitResult{
+		Limit: 3, RetryAfter: 40 * time.Second, Reset: 40 * time.Second,
+	})
+	// A rejected hit does not extend the window.
+	assertExpiry(t, server, key, 40*time.Second)
+
+	advance(40*time.Second - time.Millisecond)
+	if allow(t, limiter, 3, time.Minute).Allowed {
+		t.Fatal("hit just before the window ends was allowed")
+	}
+
+	advance(time.Millisecond)
+	assertResult(t, allow(t, limiter, 3, time.Minute), service_models.RateLimitResult{
+		Allowed: true, Limit: 3, Remaining: 2, Reset: time.Minute,
+	})
+	assertExpiry(t, server, key, time.Minute)
+}
+
+func TestSlidingWindowRateLimit(t *testing.T) {
+	limiter, server, advance := newTestRateLimiter(t, config.RateAlgorithmSlidingWindow)
+	key := "ratelimit:sliding_window:user:1"
+
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute,
+	})
+	advance(10 * time.Second)
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute,
+	})
+	assertExpiry(t, server, key, time.Minute)
+
+	// The oldest hit leaves the window 40s later, the newest one 50s later.
+	advance(10 * time.Second)
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Limit: 2, RetryAfter: 40 * time.Second, Reset: 50 * time.Second,
+	})
+	assertExpiry(t, server, key, 50*time.Second)
+
+	advance(40*time.Second - time.Millisecond)
+	if allow(t, limiter, 2, time.Minute).Allowed {
+		t.Fatal("hit just before the oldest one left the window was allowed")
+	}
+
+	advance(time.Millisecond)
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute,
+	})
+	assertExpiry(t, server, key, time.Minute)
+}
+
+func TestGCRARateLimit(t *testing.T) {
+	limiter, server, advance := newTestRateLimiter(t, config.RateAlgorithmGCRA)
+	key := "ratelimit:gcra:user:1"
+
+	// A limit of 2 per minute admits a burst of 2 and then one hit every 30s.
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second,
+	})
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute,
+	})
+	assertExpiry(t, server, key, time.Minute)
+
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Limit: 2, RetryAfter: 30 * time.Second, Reset: time.Minute,
+	})
+
+	advance(30*time.Second - time.Millisecond)
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Limit: 2, RetryAfter: time.Millisecond, Reset: 30*time.Second + time.Millisecond,
+	})
+
+	advance(time.Millisecond)
+	assertResult(t, allow(t, limiter, 2, time.Minute), service_models.RateLimitResult{
+		Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute,
+	})
+	assertExpiry(t, server, key, time.Minute)
+}
+
+func TestRateLimitZeroLimit(t *testing.T) {
+	for _, algorithm := range []string{config.RateAlgorithmFixedWindow, config.RateAlgorithmSlidingWindow, config.RateAlgorithmGCRA} {
+		t.Run(algorithm, func(t *testing.T) {
+			limiter, server, _ := newTestRateLimiter(t, algorithm)
+
+			assertResult(t, allow(t, limiter, 0, time.Minute), service_models.RateLimitResult{
+				RetryAfter: time.Minute, Reset: time.Minute,
+			})
+			if keys := server.Keys(); len(keys) != 0 {
+				t.Fatalf("rejecting a zero limit wrote %v", keys)
+			}
+		})
+	}
+}
+
+func TestRateLimitKeysExpire(t *testing.T) {
+	for _, algorithm := range []string{config.RateAlgorithmFixedWindow, config.RateAlgorithmSlidingWindow, config.RateAlgorithmGCRA} {
+		t.Run(algorithm, func(t *testing.T) {
+			limiter, server, _ := newTestRateLimiter(t, algorithm)
+
+			for i := 0; i < 5; i++ {
+				allow(t, limiter, 3, time.Minute)
+			}
+			for _, key := range server.Keys() {
+				if ttl := server.TTL(key); ttl <= 0 || ttl > time.Minute {
+					t.Fatalf("TTL of %s = %v, want within the window", key, ttl)
+				}
+			}
+
+			server.FastForward(time.Minute)
+			if keys := server.Keys(); len(keys) != 0 {
+				t.Fatalf("keys left after the window: %v", keys)
+			}
+		})
+	}
+}
+
+func TestRateLimitFallsBackToLocalGCRA(t *testing.T) {
+	client := newDownRedis(t)
+	limiter := NewRateLimitRepository(client, config.RateAlgorithmSlidingWindow)
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		result := allow(t, limiter, 2, time.Minute)
+		if !result.Allowed || result.Remaining != remaining {
+			t.Fatalf("got %+v, want an allowed hit with %d remaining", *result, remaining)
+		}
+	}
+
+	result := allow(t, limiter, 2, time.Minute)
+	if result.Allowed {
+		t.Fatal("hit over the limit was allowed while Redis is down")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > 30*time.Second {
+		t.Fatalf("RetryAfter = %v, want up to one interval", result.RetryAfter)
+	}
+}
+
+func TestRateLimitMarksRedisDownOnError(t *testing.T) {
+	server, client := newTestRedis(t)
+	limiter := NewRateLimitRepository(client, config.RateAlgorithmFixedWindow)
+	server.Close()
+
+	result := allow(t, limiter, 2, time.Minute)
+	if !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("got %+v, want the local limiter's first hit", *result)
+	}
+	if client.Healthy() {
+		t.Fatal("client still healthy after Redis failed")
+	}
+}
+
+func TestRateLimitWithoutRedis(t *testing.T) {
+	limiter := NewRateLimitRepository(nil, config.RateAlgorithmGCRA)
+
+	allow(t, limiter, 1, time.Minute)
+	if allow(t, limiter, 1, time.Minute).Allowed {
+		t.Fatal("second hit allowed with a limit of 1 and Redis disabled")
+	}
+}
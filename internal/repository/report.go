@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+)
+
+type ReportRepository interface {
+	Create(ctx context.Context, report *service_models.Report) error
+	GetQueue(ctx context.Context, q service_models.ReportQueueQuery) ([]service_models.ReportGroup, error)
+	Resolve(ctx context.Context, targetType string, targetId int64, status, resolution string, resolvedBy int64) error
+	WithTx(tx *sql.Tx) ReportRepository
+}
+
+type reportRepository struct {
+	dbRead  utils.Querier
+	dbWrite *sql.DB
+	tx      *sql.Tx
+}
+
+func (r *reportRepository) Create(ctx context.Context, report *service_models.Report) error {
+	query := `INSERT INTO reports (reporter_id, target_type, target_id, reason) VALUES ($1, $2, $3, $4) RETURNING id, status, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{report.ReporterID, report.TargetType, report.TargetID, report.Reason}
+	err := querier(r.tx, r.dbWrite).QueryRowContext(ctx, query, args...).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrsConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *reportRepository) GetQueue(ctx context.Context, q service_models.ReportQueueQuery) ([]service_models.ReportGroup, error) {
+	query := `
+		SELECT target_type, target_id, status, COUNT(*) AS report_count, ARRAY_AGG(DISTINCT reason), MIN(created_at), MAX(created_at)
+		FROM reports
+		WHERE status = $1 AND ($2 = '' OR target_type = $2)
+		GROUP BY target_type, target_id, status
+		HAVING COUNT(*) >= $3
+		ORDER BY report_count DESC, MIN(created_at) ASC
+		LIMIT $4 OFFSET $5
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(r.tx, r.dbRead).QueryContext(ctx, query, q.Status, q.TargetType, q.MinReports, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]service_models.ReportGroup, 0)
+	for rows.Next() {
+		var group service_models.ReportGroup
+		err = rows.Scan(
+			&group.TargetType,
+			&group.TargetID,
+			&group.Status,
+			&group.ReportCount,
+			pq.Array(&group.Reasons),
+			&group.FirstReportedAt,
+			&group.LastReportedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *reportRepository) Resolve(ctx context.Context, targetType string, targetId int64, status, resolution string, resolvedBy int64) error {
+	query := `
+		UPDATE reports SET status = $1, resolution = $2, resolved_by = $3, resolved_at = NOW()
+		WHERE target_type = $4 AND target_id = $5 AND status = 'open'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(r.tx, r.dbWrite).ExecContext(ctx, query, status, resolution, resolvedBy, targetType, targetId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (r *reportRepository) WithTx(tx *sql.Tx) ReportRepository {
+	return &reportRepository{
+		dbRead:  r.dbRead,
+		dbWrite: r.dbWrite,
+		tx:      tx,
+	}
+}
+
+func NewReportRepository(dbRead utils.Querier, dbWrite *sql.DB) ReportRepository {
+	return &reportRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
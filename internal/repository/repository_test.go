@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+)
+
+func TestMain(m *testing.M) {
+	config.AppConfig = &config.Config{
+		Context: config.Context{ContextTimeout: time.Second},
+		DBConfig: config.DBConfig{
+			Timeout: time.Second,
+		},
+	}
+	os.Exit(m.Run())
+}
+
+// newTestRedis starts an in-process Redis stand-in and a healthy client for
+// it. Both are closed when the test ends.
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *utils.RedisClient) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client, err := utils.RedisConnection(server.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("connecting to miniredis: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return server, client
+}
+
+// newDownRedis returns a client marked unhealthy, as after a failed call.
+func newDownRedis(t *testing.T) *utils.RedisClient {
+	t.Helper()
+
+	server, client := newTestRedis(t)
+	server.Close()
+	client.MarkDown(redis.ErrClosed)
+	return client
+}
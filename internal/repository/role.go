@@ -3,39 +3,213 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"github.com/lib/pq"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 )
 
 type RoleRepository interface {
 	GetByName(ctx context.Context, name string) (*service_models.Role, error)
+	GetById(ctx context.Context, id int64) (*service_models.Role, error)
+	GetAll(ctx context.Context) ([]service_models.Role, error)
+	Create(ctx context.Context, role *service_models.Role) error
+	Update(ctx context.Context, role *service_models.Role) error
+	GetPermissions(ctx context.Context, roleId int64) ([]string, error)
+	SetPermissions(ctx context.Context, roleId int64, permissions []string) error
+	CreateAssignment(ctx context.Context, assignment *service_models.RoleAssignment) error
 	WithTx(tx *sql.Tx) RoleRepository
 }
 
 type roleRepository struct {
-	dbRead  *sql.DB
+	dbRead  utils.Querier
 	dbWrite *sql.DB
 	tx      *sql.Tx
 }
 
 func (r *roleRepository) GetByName(ctx context.Context, name string) (*service_models.Role, error) {
-	query := `SELECT id, name, description, level FROM roles WHERE name = $1`
+	query := `SELECT id, name, COALESCE(description, ''), level FROM roles WHERE name = $1`
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
 	role := &service_models.Role{}
-	err := r.dbRead.QueryRowContext(ctx, query, name).Scan(
+	err := querier(r.tx, r.dbRead).QueryRowContext(ctx, query, name).Scan(
 		&role.ID,
 		&role.Name,
 		&role.Description,
 		&role.Level,
 	)
 	if err != nil {
-		return nil, err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return role, nil
+}
+
+func (r *roleRepository) GetById(ctx context.Context, id int64) (*service_models.Role, error) {
+	query := `SELECT id, name, COALESCE(description, ''), level FROM roles WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	role := &service_models.Role{}
+	err := querier(r.tx, r.dbRead).QueryRowContext(ctx, query, id).Scan(
+		&role.ID,
+		&role.Name,
+		&role.Description,
+		&role.Level,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
 	}
 	return role, nil
 }
 
+func (r *roleRepository) GetAll(ctx context.Context) ([]service_models.Role, error) {
+	query := `
+		SELECT r.id, r.name, COALESCE(r.description, ''), r.level, ARRAY_REMOVE(ARRAY_AGG(p.name ORDER BY p.name), NULL)
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		GROUP BY r.id
+		ORDER BY r.level, r.name
+	`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(r.tx, r.dbRead).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]service_models.Role, 0)
+	for rows.Next() {
+		var role service_models.Role
+		err = rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.Level,
+			pq.Array(&role.Permissions),
+		)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *service_models.Role) error {
+	query := `INSERT INTO roles (name, description, level) VALUES ($1, $2, $3) RETURNING id`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	err := querier(r.tx, r.dbWrite).QueryRowContext(ctx, query, role.Name, role.Description, role.Level).Scan(&role.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrsConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *service_models.Role) error {
+	query := `UPDATE roles SET description = $1, level = $2 WHERE id = $3`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(r.tx, r.dbWrite).ExecContext(ctx, query, role.Description, role.Level, role.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (r *roleRepository) GetPermissions(ctx context.Context, roleId int64) ([]string, error) {
+	query := `SELECT p.name FROM permissions p JOIN role_permissions rp ON rp.permission_id = p.id WHERE rp.role_id = $1 ORDER BY p.name`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(r.tx, r.dbRead).QueryContext(ctx, query, roleId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := make([]string, 0)
+	for rows.Next() {
+		var permission string
+		if err = rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *roleRepository) SetPermissions(ctx context.Context, roleId int64, permissions []string) error {
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	if _, err := querier(r.tx, r.dbWrite).ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleId); err != nil {
+		return err
+	}
+
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO role_permissions (role_id, permission_id) SELECT $1, id FROM permissions WHERE name = ANY($2)`
+	result, err := querier(r.tx, r.dbWrite).ExecContext(ctx, query, roleId, pq.Array(permissions))
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != int64(len(permissions)) {
+		return ErrUnknownPermission
+	}
+	return nil
+}
+
+func (r *roleRepository) CreateAssignment(ctx context.Context, assignment *service_models.RoleAssignment) error {
+	query := `INSERT INTO role_assignments (user_id, old_role_id, new_role_id, changed_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{assignment.UserID, assignment.OldRoleID, assignment.NewRoleID, assignment.ChangedBy}
+	return querier(r.tx, r.dbWrite).QueryRowContext(ctx, query, args...).Scan(&assignment.ID, &assignment.CreatedAt)
+}
+
 func (r *roleRepository) WithTx(tx *sql.Tx) RoleRepository {
 	return &roleRepository{
 		dbRead:  r.dbRead,
@@ -44,7 +218,7 @@ func (r *roleRepository) WithTx(tx *sql.Tx) RoleRepository {
 	}
 }
 
-func NewRoleRepository(dbRead, dbWrite *sql.DB) RoleRepository {
+func NewRoleRepository(dbRead utils.Querier, dbWrite *sql.DB) RoleRepository {
 	return &roleRepository{
 		dbRead:  dbRead,
 		dbWrite: dbWrite,
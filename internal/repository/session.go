@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *service_models.Session) error
+	GetById(ctx context.Context, id int64) (*service_models.Session, error)
+	GetByUserId(ctx context.Context, userId int64) ([]service_models.Session, error)
+	Touch(ctx context.Context, id int64) error
+	Revoke(ctx context.Context, id, userId int64) error
+	RevokeOthers(ctx context.Context, userId, currentId int64) error
+	WithTx(tx *sql.Tx) SessionRepository
+}
+
+type sessionRepository struct {
+	dbRead  utils.Querier
+	dbWrite *sql.DB
+	tx      *sql.Tx
+}
+
+func (s *sessionRepository) Create(ctx context.Context, session *service_models.Session) error {
+	query := `INSERT INTO sessions (user_id, user_agent, ip) VALUES ($1, $2, $3) RETURNING id, created_at, last_seen_at`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{session.UserID, session.UserAgent, session.IP}
+	if err := querier(s.tx, s.dbWrite).QueryRowContext(ctx, query, args...).Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *sessionRepository) GetById(ctx context.Context, id int64) (*service_models.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at FROM sessions WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	session := &service_models.Session{}
+	err := querier(s.tx, s.dbRead).QueryRowContext(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+func (s *sessionRepository) GetByUserId(ctx context.Context, userId int64) ([]service_models.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip, created_at, last_seen_at, revoked_at FROM sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(s.tx, s.dbRead).QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]service_models.Session, 0)
+	for rows.Next() {
+		var session service_models.Session
+		err = rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.RevokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *sessionRepository) Touch(ctx context.Context, id int64) error {
+	query := `UPDATE sessions SET last_seen_at = NOW() WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	_, err := querier(s.tx, s.dbWrite).ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *sessionRepository) Revoke(ctx context.Context, id, userId int64) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(s.tx, s.dbWrite).ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (s *sessionRepository) RevokeOthers(ctx context.Context, userId, currentId int64) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	_, err := querier(s.tx, s.dbWrite).ExecContext(ctx, query, userId, currentId)
+	return err
+}
+
+func (s *sessionRepository) WithTx(tx *sql.Tx) SessionRepository {
+	return &sessionRepository{
+		dbRead:  s.dbRead,
+		dbWrite: s.dbWrite,
+		tx:      tx,
+	}
+}
+
+func NewSessionRepository(dbRead utils.Querier, dbWrite *sql.DB) SessionRepository {
+	return &sessionRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
+)
+
+type StatsRepository interface {
+	GetActiveUsers(ctx context.Context) (*service_models.ActiveUsers, error)
+	GetDailySignups(ctx context.Context, since time.Time) ([]service_models.DailyCount, error)
+	GetDailyActivations(ctx context.Context, since time.Time) ([]service_models.DailyCount, error)
+	GetTotals(ctx context.Context) (*service_models.StatsTotals, error)
+	CountOpenReports(ctx context.Context) (int64, error)
+	GetTopTags(ctx context.Context, limit int) ([]service_models.TagCount, error)
+	WithTx(tx *sql.Tx) StatsRepository
+}
+
+type statsRepository struct {
+	dbRead  utils.Querier
+	dbWrite *sql.DB
+	tx      *sql.Tx
+}
+
+func (s *statsRepository) GetActiveUsers(ctx context.Context) (*service_models.ActiveUsers, error) {
+	query := `
+		SELECT
+			COUNT(DISTINCT user_id) FILTER (WHERE seen_at > NOW() - INTERVAL '1 day'),
+			COUNT(DISTINCT user_id) FILTER (WHERE seen_at > NOW() - INTERVAL '7 days'),
+			COUNT(DISTINCT user_id)
+		FROM (
+			SELECT user_id, last_seen_at AS seen_at FROM sessions
+			WHERE last_seen_at > NOW() - INTERVAL '30 days'
+			UNION ALL
+			SELECT user_id, last_used_at FROM personal_access_tokens
+			WHERE last_used_at > NOW() - INTERVAL '30 days'
+		) activity
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	active := &service_models.ActiveUsers{}
+	if err := querier(s.tx, s.dbRead).QueryRowContext(ctx, query).Scan(&active.Day, &active.Week, &active.Month); err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+func (s *statsRepository) GetDailySignups(ctx context.Context, since time.Time) ([]service_models.DailyCount, error) {
+	return s.dailyCounts(ctx, "created_at", since)
+}
+
+func (s *statsRepository) GetDailyActivations(ctx context.Context, since time.Time) ([]service_models.DailyCount, error) {
+	return s.dailyCounts(ctx, "activated_at", since)
+}
+
+// dailyCounts counts users per day on column, including days without any.
+// Users are grouped by day before the series is joined, so the range filter
+// can use the column's index.
+func (s *statsRepository) dailyCounts(ctx context.Context, column string, since time.Time) ([]service_models.DailyCount, error) {
+	query := `
+		SELECT to_char(d.day, 'YYYY-MM-DD'), COALESCE(c.count, 0)
+		FROM (
+			SELECT date_trunc('day', u.` + column + `) AS day, COUNT(*) AS count
+			FROM users u
+			WHERE u.` + column + ` >= date_trunc('day', $1::timestamptz)
+			GROUP BY date_trunc('day', u.` + column + `)
+		) c
+		RIGHT JOIN generate_series(date_trunc('day', $1::timestamptz), date_trunc('day', NOW()), INTERVAL '1 day') AS d(day)
+		ON c.day = d.day
+		ORDER BY d.day
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(s.tx, s.dbRead).QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]service_models.DailyCount, 0)
+	for rows.Next() {
+		var count service_models.DailyCount
+		if err = rows.Scan(&count.Date, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (s *statsRepository) GetTotals(ctx context.Context) (*service_models.StatsTotals, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM users),
+			(SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM comments WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM followers)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	totals := &service_models.StatsTotals{}
+	if err := querier(s.tx, s.dbRead).QueryRowContext(ctx, query).Scan(&totals.Users, &totals.Posts, &totals.Comments, &totals.Follows); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+func (s *statsRepository) CountOpenReports(ctx context.Context) (int64, error) {
+	query := `SELECT COUNT(*) FROM reports WHERE status = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var count int64
+	if err := querier(s.tx, s.dbRead).QueryRowContext(ctx, query, service_models.ReportStatusOpen).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *statsRepository) GetTopTags(ctx context.Context, limit int) ([]service_models.TagCount, error) {
+	query := `
+		SELECT tag, COUNT(*)
+		FROM posts, unnest(tags) AS tag
+		WHERE deleted_at IS NULL AND hidden_at IS NULL
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag
+		LIMIT $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(s.tx, s.dbRead).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]service_models.TagCount, 0)
+	for rows.Next() {
+		var tag service_models.TagCount
+		if err = rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *statsRepository) WithTx(tx *sql.Tx) StatsRepository {
+	return &statsRepository{
+		dbRead:  s.dbRead,
+		dbWrite: s.dbWrite,
+		tx:      tx,
+	}
+}
+
+func NewStatsRepository(dbRead utils.Querier, dbWrite *sql.DB) StatsRepository {
+	return &statsRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
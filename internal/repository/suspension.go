@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+)
+
+type SuspensionRepository interface {
+	Create(ctx context.Context, suspension *service_models.Suspension) error
+	GetActive(ctx context.Context, userId int64) (*service_models.Suspension, error)
+	Lift(ctx context.Context, userId int64, liftedBy *int64) error
+	WithTx(tx *sql.Tx) SuspensionRepository
+}
+
+type suspensionRepository struct {
+	dbRead  utils.Querier
+	dbWrite *sql.DB
+	tx      *sql.Tx
+}
+
+// Create suspends a user, replacing whatever suspension was active before.
+func (s *suspensionRepository) Create(ctx context.Context, suspension *service_models.Suspension) error {
+	query := `
+		WITH lifted AS (
+			UPDATE suspensions SET lifted_at = NOW(), lifted_by = $4
+			WHERE user_id = $1 AND lifted_at IS NULL
+		)
+		INSERT INTO suspensions (user_id, reason, expires_at, created_by) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{suspension.UserID, suspension.Reason, suspension.ExpiresAt, suspension.CreatedBy}
+	if err := querier(s.tx, s.dbWrite).QueryRowContext(ctx, query, args...).Scan(&suspension.ID, &suspension.CreatedAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *suspensionRepository) GetActive(ctx context.Context, userId int64) (*service_models.Suspension, error) {
+	query := `
+		SELECT id, user_id, reason, expires_at, created_by, created_at, lifted_at, lifted_by FROM suspensions
+		WHERE user_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	suspension := &service_models.Suspension{}
+	err := querier(s.tx, s.dbRead).QueryRowContext(ctx, query, userId).Scan(
+		&suspension.ID,
+		&suspension.UserID,
+		&suspension.Reason,
+		&suspension.ExpiresAt,
+		&suspension.CreatedBy,
+		&suspension.CreatedAt,
+		&suspension.LiftedAt,
+		&suspension.LiftedBy,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return suspension, nil
+}
+
+func (s *suspensionRepository) Lift(ctx context.Context, userId int64, liftedBy *int64) error {
+	query := `
+		UPDATE suspensions SET lifted_at = NOW(), lifted_by = $2
+		WHERE user_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(s.tx, s.dbWrite).ExecContext(ctx, query, userId, liftedBy)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (s *suspensionRepository) WithTx(tx *sql.Tx) SuspensionRepository {
+	return &suspensionRepository{
+		dbRead:  s.dbRead,
+		dbWrite: s.dbWrite,
+		tx:      tx,
+	}
+}
+
+func NewSuspensionRepository(dbRead utils.Querier, dbWrite *sql.DB) SuspensionRepository {
+	return &suspensionRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"strconv"
+	"time"
+)
+
+// TimelineRepository keeps each user's home timeline as a Redis sorted set of
+// post ids scored by creation time in milliseconds. A timeline only exists
+// once it has been built with Replace; Add leaves missing timelines alone so a
+// partial one is never mistaken for a complete one. While Redis is
+// unavailable every timeline reads as missing and writes are skipped.
+type TimelineRepository interface {
+	// Available reports whether timelines can be read and written.
+	Available() bool
+	// Range returns up to count of the newest entries and reports whether the
+	// timeline exists. Reading a timeline renews its expiry.
+	Range(ctx context.Context, userId int64, count int) ([]service_models.TimelineEntry, bool, error)
+	Add(ctx context.Context, userIds []int64, entries []service_models.TimelineEntry) error
+	Remove(ctx context.Context, userIds []int64, postIds []int64) error
+	Replace(ctx context.Context, userId int64, entries []service_models.TimelineEntry) error
+}
+
+// timelineSentinel keeps an empty timeline's key alive. Its score sorts it
+// below every post, so trimming by rank skips it.
+const timelineSentinel = "0"
+
+// timelineBatchSize is how many timelines one pipeline writes to.
+const timelineBatchSize = 500
+
+// timelineAddScript adds the score and member pairs after ARGV[1] to an
+// existing timeline and trims it to ARGV[1] posts.
+var timelineAddScript = redis.NewScript(`
+	if redis.call('EXISTS', KEYS[1]) == 0 then
+		return 0
+	end
+	for i = 2, #ARGV, 2 do
+		redis.call('ZADD', KEYS[1], ARGV[i], ARGV[i + 1])
+	end
+	redis.call('ZREMRANGEBYRANK', KEYS[1], 1, -(tonumber(ARGV[1]) + 1))
+	return 1
+`)
+
+func timelineKey(userId int64) string {
+	return fmt.Sprintf("timeline:%d", userId)
+}
+
+type timelineRepository struct {
+	client    *utils.RedisClient
+	maxLength int
+	ttl       time.Duration
+}
+
+func (t *timelineRepository) Available() bool {
+	return t.client.Healthy()
+}
+
+func (t *timelineRepository) Range(ctx context.Context, userId int64, count int) ([]service_models.TimelineEntry, bool, error) {
+	if !t.client.Healthy() || count <= 0 {
+		return nil, false, nil
+	}
+
+	key := timelineKey(userId)
+	pipe := t.client.Pipeline()
+	members := pipe.ZRevRangeWithScores(ctx, key, 0, int64(count))
+	exists := pipe.PExpire(ctx, key, t.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.client.MarkDown(err)
+		return nil, false, nil
+	}
+	if !exists.Val() {
+		return nil, false, nil
+	}
+
+	entries := make([]service_models.TimelineEntry, 0, count)
+	for _, member := range members.Val() {
+		id, err := strconv.ParseInt(fmt.Sprint(member.Member), 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		entries = append(entries, service_models.TimelineEntry{
+			PostID:    id,
+			CreatedAt: time.UnixMilli(int64(member.Score)),
+		})
+	}
+	if len(entries) > count {
+		entries = entries[:count]
+	}
+	return entries, true, nil
+}
+
+func (t *timelineRepository) Add(ctx context.Context, userIds []int64, entries []service_models.TimelineEntry) error {
+	if !t.client.Healthy() || len(userIds) == 0 || len(entries) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, 1+2*len(entries))
+	args = append(args, t.maxLength)
+	for _, entry := range entries {
+		args = append(args, entry.CreatedAt.UnixMilli(), entry.PostID)
+	}
+
+	return t.batch(ctx, userIds, func(pipe redis.Pipeliner, key string) {
+		timelineAddScript.Eval(ctx, pipe, []string{key}, args...)
+	})
+}
+
+func (t *timelineRepository) Remove(ctx context.Context, userIds []int64, postIds []int64) error {
+	if !t.client.Healthy() || len(userIds) == 0 || len(postIds) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(postIds))
+	for i, id := range postIds {
+		members[i] = id
+	}
+
+	return t.batch(ctx, userIds, func(pipe redis.Pipeliner, key string) {
+		pipe.ZRem(ctx, key, members...)
+	})
+}
+
+// Replace builds a user's timeline from scratch in one transaction.
+func (t *timelineRepository) Replace(ctx context.Context, userId int64, entries []service_models.TimelineEntry) error {
+	if !t.client.Healthy() {
+		return nil
+	}
+
+	members := make([]*redis.Z, 0, len(entries)+1)
+	members = append(members, &redis.Z{Score: 0, Member: timelineSentinel})
+	for _, entry := range entries {
+		members = append(members, &redis.Z{Score: float64(entry.CreatedAt.UnixMilli()), Member: entry.PostID})
+	}
+
+	key := timelineKey(userId)
+	_, err := t.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByRank(ctx, key, 1, -int64(t.maxLength+1))
+		pipe.PExpire(ctx, key, t.ttl)
+		return nil
+	})
+	if err != nil {
+		t.client.MarkDown(err)
+	}
+	return nil
+}
+
+// batch queues one command per timeline and sends them in pipelines of
+// timelineBatchSize.
+func (t *timelineRepository) batch(ctx context.Context, userIds []int64, queue func(pipe redis.Pipeliner, key string)) error {
+	for start := 0; start < len(userIds); start += timelineBatchSize {
+		end := min(start+timelineBatchSize, len(userIds))
+
+		pipe := t.client.Pipeline()
+		for _, id := range userIds[start:end] {
+			queue(pipe, timelineKey(id))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			t.client.MarkDown(err)
+			return nil
+		}
+	}
+	return nil
+}
+
+func NewTimelineRepository(client *utils.RedisClient, maxLength int, ttl time.Duration) TimelineRepository {
+	return &timelineRepository{
+		client:    client,
+		maxLength: maxLength,
+		ttl:       ttl,
+	}
+}
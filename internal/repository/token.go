@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, token *service_models.PersonalAccessToken, hash string) error
+	GetByHash(ctx context.Context, hash string) (*service_models.PersonalAccessToken, error)
+	GetByUserId(ctx context.Context, userId int64) ([]service_models.PersonalAccessToken, error)
+	Touch(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id, userId int64) error
+	WithTx(tx *sql.Tx) PersonalAccessTokenRepository
+}
+
+type personalAccessTokenRepository struct {
+	dbRead  utils.Querier
+	dbWrite *sql.DB
+	tx      *sql.Tx
+}
+
+func (p *personalAccessTokenRepository) Create(ctx context.Context, token *service_models.PersonalAccessToken, hash string) error {
+	query := `INSERT INTO personal_access_tokens (user_id, name, token, scopes, expiry) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{token.UserID, token.Name, hash, pq.Array(token.Scopes), token.Expiry}
+	if err := querier(p.tx, p.dbWrite).QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *personalAccessTokenRepository) GetByHash(ctx context.Context, hash string) (*service_models.PersonalAccessToken, error) {
+	query := `SELECT id, user_id, name, scopes, expiry, last_used_at, created_at FROM personal_access_tokens WHERE token = $1 AND expiry > $2`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	token := &service_models.PersonalAccessToken{}
+	err := querier(p.tx, p.dbRead).QueryRowContext(ctx, query, hash, time.Now()).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		pq.Array(&token.Scopes),
+		&token.Expiry,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+func (p *personalAccessTokenRepository) GetByUserId(ctx context.Context, userId int64) ([]service_models.PersonalAccessToken, error) {
+	query := `SELECT id, user_id, name, scopes, expiry, last_used_at, created_at FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(p.tx, p.dbRead).QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]service_models.PersonalAccessToken, 0)
+	for rows.Next() {
+		var token service_models.PersonalAccessToken
+		err = rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Name,
+			pq.Array(&token.Scopes),
+			&token.Expiry,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (p *personalAccessTokenRepository) Touch(ctx context.Context, id int64) error {
+	query := `UPDATE personal_access_tokens SET last_used_at = NOW() WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	_, err := querier(p.tx, p.dbWrite).ExecContext(ctx, query, id)
+	return err
+}
+
+func (p *personalAccessTokenRepository) Delete(ctx context.Context, id, userId int64) error {
+	query := `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(p.tx, p.dbWrite).ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (p *personalAccessTokenRepository) WithTx(tx *sql.Tx) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{
+		dbRead:  p.dbRead,
+		dbWrite: p.dbWrite,
+		tx:      tx,
+	}
+}
+
+func NewPersonalAccessTokenRepository(dbRead utils.Querier, dbWrite *sql.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{
+		dbRead:  dbRead,
+		dbWrite: dbWrite,
+	}
+}
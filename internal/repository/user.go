@@ -8,6 +8,7 @@ import (
 	"errors"
 	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 	"time"
 )
 
@@ -20,11 +21,24 @@ type UserRepository interface {
 	UpdateUserInvitation(ctx context.Context, user *service_models.User) error
 	DeleteUserInvitation(ctx context.Context, id int64) error
 	Delete(ctx context.Context, id int64) error
+	GetRoleId(ctx context.Context, id int64) (int64, error)
+	SetRole(ctx context.Context, id, roleId int64) error
+	Activate(ctx context.Context, id int64) error
+	GetShadowBan(ctx context.Context, id int64) (bool, error)
+	SetShadowBan(ctx context.Context, id int64, banned bool) error
+	PurgeInvitations(ctx context.Context, expiredBefore time.Time) (int64, error)
+	UpdatePassword(ctx context.Context, id int64, hash []byte) error
+	UpdateEmail(ctx context.Context, id int64, email string) error
+	CreateEmailChange(ctx context.Context, change *service_models.EmailChange, token, cancelToken string) error
+	GetEmailChange(ctx context.Context, token string) (*service_models.EmailChange, error)
+	GetEmailChangeByCancelToken(ctx context.Context, cancelToken string) (*service_models.EmailChange, error)
+	DeleteEmailChange(ctx context.Context, userId int64) error
+	ListIds(ctx context.Context, afterId int64, limit int) ([]int64, error)
 	WithTx(tx *sql.Tx) UserRepository
 }
 
 type userRepository struct {
-	dbRead  *sql.DB
+	dbRead  utils.Querier
 	dbWrite *sql.DB
 	tx      *sql.Tx
 }
@@ -45,26 +59,59 @@ func (u *userRepository) Create(ctx context.Context, user *service_models.User)
 	}
 
 	args := []any{user.Username, user.Password.Hash, user.Email, role}
-	if err := u.dbWrite.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt); err != nil {
-		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_username_key"`:
-			return ErrDuplicateUsername
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
-			return ErrDuplicateEmail
-		default:
-			return err
-		}
+	if err := querier(u.tx, u.dbWrite).QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt); err != nil {
+		return duplicateUserError(err)
 	}
 
 	return nil
 }
 
+func duplicateUserError(err error) error {
+	switch {
+	case err.Error() == `pq: duplicate key value violates unique constraint "users_username_key"`:
+		return ErrDuplicateUsername
+	case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
+		return ErrDuplicateEmail
+	default:
+		return err
+	}
+}
+
 func (u *userRepository) GetById(ctx context.Context, id int64) (*service_models.User, error) {
-	query := `SELECT users.id, username, email, password, created_at, roles.* FROM users JOIN roles ON (users.role_id = roles.id) WHERE users.id = $1 AND is_active = true`
+	query := `
+		SELECT users.id, username, email, password, users.created_at, roles.*, s.id, s.reason, s.expires_at, s.created_by, s.created_at
+		FROM users
+		JOIN roles ON (users.role_id = roles.id)
+		LEFT JOIN LATERAL (
+			SELECT id, reason, expires_at, created_by, created_at FROM suspensions
+			WHERE user_id = users.id AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+			ORDER BY created_at DESC LIMIT 1
+		) s ON true
+		WHERE users.id = $1 AND is_active = true
+	`
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 	var user service_models.User
-	err := u.dbRead.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Username, &user.Email, &user.Password.Hash, &user.CreatedAt, &user.Role.ID, &user.Role.Name, &user.Role.Level, &user.Role.Description)
+	var suspensionId sql.NullInt64
+	var suspensionReason sql.NullString
+	var suspensionCreatedAt sql.NullTime
+	var suspension service_models.Suspension
+	err := querier(u.tx, u.dbRead).QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Password.Hash,
+		&user.CreatedAt,
+		&user.Role.ID,
+		&user.Role.Name,
+		&user.Role.Level,
+		&user.Role.Description,
+		&suspensionId,
+		&suspensionReason,
+		&suspension.ExpiresAt,
+		&suspension.CreatedBy,
+		&suspensionCreatedAt,
+	)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -74,6 +121,14 @@ func (u *userRepository) GetById(ctx context.Context, id int64) (*service_models
 		}
 	}
 
+	if suspensionId.Valid {
+		suspension.ID = suspensionId.Int64
+		suspension.UserID = user.ID
+		suspension.Reason = suspensionReason.String
+		suspension.CreatedAt = suspensionCreatedAt.Time
+		user.Suspension = &suspension
+	}
+
 	return &user, nil
 }
 
@@ -83,7 +138,7 @@ func (u *userRepository) CreateUserInvitation(ctx context.Context, token string,
 	defer cancel()
 
 	args := []any{token, id, time.Now().Add(exp)}
-	if _, err := u.dbWrite.ExecContext(ctx, query, args...); err != nil {
+	if _, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, args...); err != nil {
 		return err
 	}
 	return nil
@@ -98,7 +153,7 @@ func (u *userRepository) GetUserFromInvitation(ctx context.Context, token string
 	hashToken := hex.EncodeToString(hash[:])
 	user := &service_models.User{}
 
-	if err := u.dbRead.QueryRowContext(ctx, query, hashToken, time.Now()).Scan(
+	if err := querier(u.tx, u.dbRead).QueryRowContext(ctx, query, hashToken, time.Now()).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -121,7 +176,7 @@ func (u *userRepository) DeleteUserInvitation(ctx context.Context, id int64) err
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
-	_, err := u.dbWrite.ExecContext(ctx, query, id)
+	_, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -129,12 +184,12 @@ func (u *userRepository) DeleteUserInvitation(ctx context.Context, id int64) err
 }
 
 func (u *userRepository) UpdateUserInvitation(ctx context.Context, user *service_models.User) error {
-	query := `UPDATE users SET username = $1, email = $2, is_active = $3 WHERE id = $4`
+	query := `UPDATE users SET username = $1, email = $2, is_active = $3, activated_at = CASE WHEN $3 AND activated_at IS NULL THEN NOW() ELSE activated_at END WHERE id = $4`
 
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
 
-	_, err := u.dbWrite.ExecContext(ctx, query, user.Username, user.Email, user.IsActive, user.ID)
+	_, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, user.Username, user.Email, user.IsActive, user.ID)
 	if err != nil {
 		return err
 	}
@@ -146,13 +201,219 @@ func (u *userRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM user_invitations WHERE user_id = $1`
 	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
 	defer cancel()
-	_, err := u.dbWrite.ExecContext(ctx, query, id)
+	_, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetRoleId returns the role of any account, activated or not.
+func (u *userRepository) GetRoleId(ctx context.Context, id int64) (int64, error) {
+	query := `SELECT role_id FROM users WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var roleId int64
+	if err := querier(u.tx, u.dbRead).QueryRowContext(ctx, query, id).Scan(&roleId); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrsNotFound
+		default:
+			return 0, err
+		}
+	}
+	return roleId, nil
+}
+
+func (u *userRepository) SetRole(ctx context.Context, id, roleId int64) error {
+	query := `UPDATE users SET role_id = $1 WHERE id = $2`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, roleId, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (u *userRepository) Activate(ctx context.Context, id int64) error {
+	query := `UPDATE users SET is_active = true, activated_at = COALESCE(activated_at, NOW()) WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (u *userRepository) GetShadowBan(ctx context.Context, id int64) (bool, error) {
+	query := `SELECT shadow_banned FROM users WHERE id = $1`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	var banned bool
+	if err := querier(u.tx, u.dbRead).QueryRowContext(ctx, query, id).Scan(&banned); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, ErrsNotFound
+		default:
+			return false, err
+		}
+	}
+	return banned, nil
+}
+
+func (u *userRepository) SetShadowBan(ctx context.Context, id int64, banned bool) error {
+	query := `UPDATE users SET shadow_banned = $1 WHERE id = $2`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, banned, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (u *userRepository) PurgeInvitations(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	query := `DELETE FROM user_invitations WHERE expiry < $1`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, expiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (u *userRepository) UpdatePassword(ctx context.Context, id int64, hash []byte) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, hash, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
+	return nil
+}
+
+func (u *userRepository) UpdateEmail(ctx context.Context, id int64, email string) error {
+	query := `UPDATE users SET email = $1 WHERE id = $2`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	result, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, email, id)
+	if err != nil {
+		return duplicateUserError(err)
+	}
+
+	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if rows == 0 {
+		return ErrsNotFound
+	}
 	return nil
 }
 
+func (u *userRepository) CreateEmailChange(ctx context.Context, change *service_models.EmailChange, token, cancelToken string) error {
+	query := `
+		INSERT INTO email_changes (token, cancel_token, user_id, old_email, new_email, expiry) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, cancel_token = EXCLUDED.cancel_token, old_email = EXCLUDED.old_email,
+		new_email = EXCLUDED.new_email, expiry = EXCLUDED.expiry, created_at = NOW()
+		RETURNING created_at
+	`
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	args := []any{token, cancelToken, change.UserID, change.OldEmail, change.NewEmail, change.Expiry}
+	return querier(u.tx, u.dbWrite).QueryRowContext(ctx, query, args...).Scan(&change.CreatedAt)
+}
+
+func (u *userRepository) GetEmailChange(ctx context.Context, token string) (*service_models.EmailChange, error) {
+	query := `SELECT user_id, old_email, new_email, expiry, created_at FROM email_changes WHERE token = $1 AND expiry > $2`
+	return u.getEmailChange(ctx, query, token)
+}
+
+func (u *userRepository) GetEmailChangeByCancelToken(ctx context.Context, cancelToken string) (*service_models.EmailChange, error) {
+	query := `SELECT user_id, old_email, new_email, expiry, created_at FROM email_changes WHERE cancel_token = $1 AND expiry > $2`
+	return u.getEmailChange(ctx, query, cancelToken)
+}
+
+func (u *userRepository) getEmailChange(ctx context.Context, query, token string) (*service_models.EmailChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	hash := sha256.Sum256([]byte(token))
+	hashToken := hex.EncodeToString(hash[:])
+	change := &service_models.EmailChange{}
+
+	if err := querier(u.tx, u.dbRead).QueryRowContext(ctx, query, hashToken, time.Now()).Scan(
+		&change.UserID,
+		&change.OldEmail,
+		&change.NewEmail,
+		&change.Expiry,
+		&change.CreatedAt,
+	); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, ErrsNotFound
+		default:
+			return nil, err
+		}
+	}
+	return change, nil
+}
+
+func (u *userRepository) DeleteEmailChange(ctx context.Context, userId int64) error {
+	query := `DELETE FROM email_changes WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	_, err := querier(u.tx, u.dbWrite).ExecContext(ctx, query, userId)
+	return err
+}
+
 func (u *userRepository) GetByEmail(ctx context.Context, email string) (*service_models.User, error) {
 	query := `
 		SELECT id, username, email, password, created_at FROM users
@@ -163,7 +424,7 @@ func (u *userRepository) GetByEmail(ctx context.Context, email string) (*service
 	defer cancel()
 
 	user := &service_models.User{}
-	err := u.dbRead.QueryRowContext(ctx, query, email).Scan(
+	err := querier(u.tx, u.dbRead).QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
@@ -182,6 +443,30 @@ func (u *userRepository) GetByEmail(ctx context.Context, email string) (*service
 	return user, nil
 }
 
+// ListIds pages through activated user ids in ascending order.
+func (u *userRepository) ListIds(ctx context.Context, afterId int64, limit int) ([]int64, error) {
+	query := `SELECT id FROM users WHERE id > $1 AND is_active = true ORDER BY id LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	rows, err := querier(u.tx, u.dbRead).QueryContext(ctx, query, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0, limit)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (u *userRepository) WithTx(tx *sql.Tx) UserRepository {
 	return &userRepository{
 		dbRead:  u.dbRead,
@@ -190,7 +475,7 @@ func (u *userRepository) WithTx(tx *sql.Tx) UserRepository {
 	}
 }
 
-func NewUserRepository(dbRead, dbWrite *sql.DB) UserRepository {
+func NewUserRepository(dbRead utils.Querier, dbWrite *sql.DB) UserRepository {
 	return &userRepository{
 		dbRead:  dbRead,
 		dbWrite: dbWrite,
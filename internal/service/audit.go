@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, log *service_models.AuditLog, before, after any) error
+	GetAll(ctx context.Context, q service_models.AuditQuery) ([]service_models.AuditLog, error)
+	RecordLoginFailure(ctx context.Context, log *service_models.AuditLog) error
+	ClearLoginFailures(ctx context.Context, userId int64) (int, error)
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+// Record stores an audit entry. before and after are serialized as JSON and
+// may be nil when the action has no previous or resulting state.
+func (a *auditService) Record(ctx context.Context, log *service_models.AuditLog, before, after any) error {
+	var err error
+	if before != nil {
+		if log.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if log.After, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+	return a.auditRepo.Create(ctx, log)
+}
+
+// RecordLoginFailure counts a failed login against the user log targets. Only
+// the first failure since the last successful login is written to the audit
+// log, so guessing at a password cannot grow the append-only table; the
+// running count is kept in login_failures instead.
+func (a *auditService) RecordLoginFailure(ctx context.Context, log *service_models.AuditLog) error {
+	attempts, err := a.auditRepo.CountLoginFailure(ctx, *log.TargetID, log.IP)
+	if err != nil || attempts > 1 {
+		return err
+	}
+	return a.auditRepo.Create(ctx, log)
+}
+
+// ClearLoginFailures resets the user's failed login count after a successful
+// login and returns how many failures preceded it.
+func (a *auditService) ClearLoginFailures(ctx context.Context, userId int64) (int, error) {
+	return a.auditRepo.ClearLoginFailures(ctx, userId)
+}
+
+func (a *auditService) GetAll(ctx context.Context, q service_models.AuditQuery) ([]service_models.AuditLog, error) {
+	return a.auditRepo.GetAll(ctx, q)
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
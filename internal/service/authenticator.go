@@ -3,6 +3,8 @@ package service
 import (
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"time"
 )
 
 type Authenticator interface {
@@ -39,6 +41,20 @@ func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 	)
 }
 
+// SessionClaims are the claims of an access token issued for a session.
+func SessionClaims(userId, sessionId int64, ttl time.Duration) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub": userId,
+		"sid": sessionId,
+		"exp": now.Add(ttl).Unix(),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"iss": config.AppConfig.Authentication.Iss,
+		"aud": config.AppConfig.Authentication.Aud,
+	}
+}
+
 func NewJWTAuthenticator(secret, aud, iss string) Authenticator {
 	return &JWTAuthenticator{
 		secret: secret,
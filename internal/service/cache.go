@@ -2,29 +2,128 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
 )
 
+// Cache stores values of one type as JSON with a fixed TTL. Lookups that
+// miss load the value once per key no matter how many callers miss at the
+// same time.
+type Cache[T any] struct {
+	store  repository.CacheRepository
+	ttl    time.Duration
+	flight utils.SingleFlight[[]byte]
+}
+
+// Get reports whether key was cached. A value that cannot be read counts as
+// a miss.
+func (c *Cache[T]) Get(ctx context.Context, key string) (T, bool) {
+	var value T
+	data, err := c.store.Get(ctx, key)
+	if err != nil || data == nil {
+		return value, false
+	}
+	if err = json.Unmarshal(data, &value); err != nil {
+		return value, false
+	}
+	return value, true
+}
+
+func (c *Cache[T]) Set(ctx context.Context, key string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, key, data, c.ttl)
+}
+
+func (c *Cache[T]) Delete(ctx context.Context, keys ...string) error {
+	return c.store.Delete(ctx, keys...)
+}
+
+// Fetch returns the cached value of key, calling load and caching its result
+// on a miss. Callers that miss together share one load, which runs detached
+// from the first caller's cancellation so the others do not fail with it.
+// Each caller decodes its own copy of the result.
+func (c *Cache[T]) Fetch(ctx context.Context, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	if value, ok := c.Get(ctx, key); ok {
+		return value, nil
+	}
+
+	var value T
+	data, err := c.flight.Do(key, func() ([]byte, error) {
+		ctx := context.WithoutCancel(ctx)
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		_ = c.store.Set(ctx, key, data, c.ttl)
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+func NewCache[T any](store repository.CacheRepository, ttl time.Duration) *Cache[T] {
+	return &Cache[T]{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// CacheService caches users for both authentication and profiles. Every
+// load goes to the primary, since authentication trusts the entry for the
+// whole TTL and a replica could still have a user from before a suspension
+// or role change.
 type CacheService interface {
-	Get(ctx context.Context, id int64) (*service_models.User, error)
-	Set(ctx context.Context, user *service_models.User) error
+	Fetch(ctx context.Context, id int64, load func(ctx context.Context) (*service_models.User, error)) (*service_models.User, error)
+	Delete(ctx context.Context, id int64) error
 }
 
 type cacheService struct {
-	cacheRepository repository.CacheRepository
+	users *Cache[cachedUser]
+}
+
+// cachedUser is a cached user. Suspension is not part of the API response, so
+// it is kept next to the user rather than inside it.
+type cachedUser struct {
+	User       service_models.User        `json:"user"`
+	Suspension *service_models.Suspension `json:"suspension"`
 }
 
-func (s *cacheService) Get(ctx context.Context, id int64) (*service_models.User, error) {
-	return s.cacheRepository.Get(ctx, id)
+func (s *cacheService) Fetch(ctx context.Context, id int64, load func(ctx context.Context) (*service_models.User, error)) (*service_models.User, error) {
+	entry, err := s.users.Fetch(ctx, repository.UserCacheKey(id), func(ctx context.Context) (cachedUser, error) {
+		user, err := load(utils.WithPrimary(ctx))
+		if err != nil {
+			return cachedUser{}, err
+		}
+		return cachedUser{User: *user, Suspension: user.Suspension}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user := entry.User
+	user.Suspension = entry.Suspension
+	return &user, nil
 }
 
-func (s *cacheService) Set(ctx context.Context, user *service_models.User) error {
-	return s.cacheRepository.Set(ctx, user)
+func (s *cacheService) Delete(ctx context.Context, id int64) error {
+	return s.users.Delete(ctx, repository.UserCacheKey(id))
 }
 
-func NewCacheService(cacheRepository repository.CacheRepository) CacheService {
+func NewCacheService(cacheRepository repository.CacheRepository, userTTL time.Duration) CacheService {
 	return &cacheService{
-		cacheRepository: cacheRepository,
+		users: NewCache[cachedUser](cacheRepository, userTTL),
 	}
 }
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+)
+
+func TestCacheFetchSurvivesFirstCallerCancelling(t *testing.T) {
+	cache := NewCache[service_models.Post](repository.NewMemoryCacheRepository(10), time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(ctx context.Context) (service_models.Post, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return service_models.Post{}, err
+		}
+		return service_models.Post{ID: 1, Tags: []string{"go"}}, nil
+	}
+
+	type result struct {
+		post service_models.Post
+		err  error
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan result, 1)
+	go func() {
+		post, err := cache.Fetch(ctx, "post-1", load)
+		first <- result{post, err}
+	}()
+	<-started
+
+	waiter := make(chan result, 1)
+	go func() {
+		post, err := cache.Fetch(context.Background(), "post-1", load)
+		waiter <- result{post, err}
+	}()
+
+	// Give the second caller time to join the load in flight.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(release)
+
+	a, b := <-first, <-waiter
+	if a.err != nil {
+		t.Fatalf("first caller: %v", a.err)
+	}
+	if b.err != nil {
+		t.Fatalf("waiting caller failed with the first caller: %v", b.err)
+	}
+
+	b.post.Tags[0] = "changed"
+	if a.post.Tags[0] != "go" {
+		t.Fatalf("Tags = %v after another caller changed its copy", a.post.Tags)
+	}
+}
@@ -7,24 +7,43 @@ import (
 )
 
 type CommentService interface {
-	GetByPostId(ctx context.Context, id int64) ([]service_models.Comment, error)
+	GetByPostId(ctx context.Context, id, viewerId int64) ([]service_models.Comment, error)
 	Create(ctx context.Context, comment *service_models.Comment) error
+	Restore(ctx context.Context, id int64) error
 }
 
 type commentService struct {
-	commentRepo repository.CommentRepository
+	commentRepo     repository.CommentRepository
+	velocityService VelocityService
+	cacheRepository repository.CacheRepository
 }
 
-func (c *commentService) GetByPostId(ctx context.Context, id int64) ([]service_models.Comment, error) {
-	return c.commentRepo.GetByPostId(ctx, id)
+func (c *commentService) GetByPostId(ctx context.Context, id, viewerId int64) ([]service_models.Comment, error) {
+	return c.commentRepo.GetByPostId(ctx, id, viewerId)
 }
 
 func (c *commentService) Create(ctx context.Context, comment *service_models.Comment) error {
-	return c.commentRepo.Create(ctx, comment)
+	if err := c.velocityService.Check(ctx, VelocityComment, comment.UserID); err != nil {
+		return err
+	}
+	if err := c.commentRepo.Create(ctx, comment); err != nil {
+		return err
+	}
+	return c.cacheRepository.Delete(ctx, repository.CommentCountCacheKey(comment.PostID))
+}
+
+func (c *commentService) Restore(ctx context.Context, id int64) error {
+	postId, err := c.commentRepo.Restore(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.cacheRepository.Delete(ctx, repository.CommentCountCacheKey(postId))
 }
 
-func NewCommentService(commentRepo repository.CommentRepository) CommentService {
+func NewCommentService(commentRepo repository.CommentRepository, velocityService VelocityService, cacheRepository repository.CacheRepository) CommentService {
 	return &commentService{
-		commentRepo: commentRepo,
+		commentRepo:     commentRepo,
+		velocityService: velocityService,
+		cacheRepository: cacheRepository,
 	}
 }
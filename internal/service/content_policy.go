@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ContentRule inspects a post or comment and decides whether it may be
+// published as is, published and flagged for review, or rejected.
+type ContentRule interface {
+	Name() string
+	Check(ctx context.Context, content *service_models.Content) (service_models.ContentVerdict, error)
+}
+
+type ContentPolicyService interface {
+	Evaluate(ctx context.Context, content *service_models.Content) (*service_models.ContentEvaluation, error)
+	Flag(ctx context.Context, content *service_models.Content, flags []service_models.ContentVerdict) error
+}
+
+type contentPolicyService struct {
+	rules      []ContentRule
+	reportRepo repository.ReportRepository
+}
+
+func (c *contentPolicyService) Evaluate(ctx context.Context, content *service_models.Content) (*service_models.ContentEvaluation, error) {
+	evaluation := &service_models.ContentEvaluation{}
+	for _, rule := range c.rules {
+		verdict, err := rule.Check(ctx, content)
+		if err != nil {
+			return nil, err
+		}
+		verdict.Rule = rule.Name()
+
+		switch verdict.Decision {
+		case service_models.ContentDecisionReject:
+			evaluation.Rejection = &verdict
+			return evaluation, nil
+		case service_models.ContentDecisionFlag:
+			evaluation.Flags = append(evaluation.Flags, verdict)
+		}
+	}
+	return evaluation, nil
+}
+
+// Flag files a report without a reporter for every flag raised against stored
+// content, putting it in the moderation queue.
+func (c *contentPolicyService) Flag(ctx context.Context, content *service_models.Content, flags []service_models.ContentVerdict) error {
+	for _, flag := range flags {
+		report := &service_models.Report{
+			TargetType: content.Type,
+			TargetID:   content.ID,
+			Reason:     fmt.Sprintf("%s: %s", flag.Rule, flag.Reason),
+		}
+		if err := c.reportRepo.Create(ctx, report); err != nil && !errors.Is(err, repository.ErrsConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewContentPolicyService(reportRepo repository.ReportRepository, rules ...ContentRule) ContentPolicyService {
+	return &contentPolicyService{
+		rules:      rules,
+		reportRepo: reportRepo,
+	}
+}
+
+type bannedWordsRule struct {
+	words    map[string]struct{}
+	decision string
+}
+
+func (b *bannedWordsRule) Name() string {
+	return "banned_words"
+}
+
+func (b *bannedWordsRule) Check(ctx context.Context, content *service_models.Content) (service_models.ContentVerdict, error) {
+	if len(b.words) == 0 {
+		return allow(), nil
+	}
+
+	text := strings.ToLower(content.Title + " " + content.Body)
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, field := range fields {
+		if _, ok := b.words[field]; ok {
+			return service_models.ContentVerdict{
+				Decision: b.decision,
+				Reason:   fmt.Sprintf("contains the banned word %q", field),
+			}, nil
+		}
+	}
+	return allow(), nil
+}
+
+// NewBannedWordsRule matches whole words case-insensitively. decision is
+// either reject or flag.
+func NewBannedWordsRule(words []string, decision string) ContentRule {
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			set[word] = struct{}{}
+		}
+	}
+	if decision != service_models.ContentDecisionFlag {
+		decision = service_models.ContentDecisionReject
+	}
+	return &bannedWordsRule{
+		words:    set,
+		decision: decision,
+	}
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+type linkLimitRule struct {
+	maxPostLinks    int
+	maxCommentLinks int
+}
+
+func (l *linkLimitRule) Name() string {
+	return "link_limit"
+}
+
+func (l *linkLimitRule) Check(ctx context.Context, content *service_models.Content) (service_models.ContentVerdict, error) {
+	limit := l.maxPostLinks
+	if content.Type == service_models.ReportTargetComment {
+		limit = l.maxCommentLinks
+	}
+
+	links := len(linkPattern.FindAllString(content.Title+" "+content.Body, -1))
+	if links > limit {
+		return service_models.ContentVerdict{
+			Decision: service_models.ContentDecisionReject,
+			Reason:   fmt.Sprintf("contains %d links, at most %d are allowed", links, limit),
+		}, nil
+	}
+	return allow(), nil
+}
+
+func NewLinkLimitRule(maxPostLinks, maxCommentLinks int) ContentRule {
+	return &linkLimitRule{
+		maxPostLinks:    maxPostLinks,
+		maxCommentLinks: maxCommentLinks,
+	}
+}
+
+type repeatedContentRule struct {
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+	window      time.Duration
+}
+
+func (r *repeatedContentRule) Name() string {
+	return "repeated_content"
+}
+
+func (r *repeatedContentRule) Check(ctx context.Context, content *service_models.Content) (service_models.ContentVerdict, error) {
+	since := time.Now().Add(-r.window)
+
+	var count int
+	var err error
+	switch content.Type {
+	case service_models.ReportTargetComment:
+		count, err = r.commentRepo.CountRecentByContent(ctx, content.UserID, content.ID, content.Body, since)
+	default:
+		count, err = r.postRepo.CountRecentByContent(ctx, content.UserID, content.ID, content.Body, since)
+	}
+	if err != nil {
+		return service_models.ContentVerdict{}, err
+	}
+
+	if count > 0 {
+		return service_models.ContentVerdict{
+			Decision: service_models.ContentDecisionReject,
+			Reason:   fmt.Sprintf("identical %s was already posted in the last %s", content.Type, r.window),
+		}, nil
+	}
+	return allow(), nil
+}
+
+// NewRepeatedContentRule rejects content identical to something the same user
+// posted within window.
+func NewRepeatedContentRule(postRepo repository.PostRepository, commentRepo repository.CommentRepository, window time.Duration) ContentRule {
+	return &repeatedContentRule{
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		window:      window,
+	}
+}
+
+func allow() service_models.ContentVerdict {
+	return service_models.ContentVerdict{Decision: service_models.ContentDecisionAllow}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"strings"
+)
+
+// diffLines returns a line diff turning a into b, built from the longest
+// common subsequence of their lines. Posts are short, so the quadratic table
+// is fine.
+func diffLines(a, b string) []service_models.DiffLine {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := make([]service_models.DiffLine, 0, max(len(x), len(y)))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			diff = append(diff, service_models.DiffLine{Op: service_models.DiffOpEqual, Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, service_models.DiffLine{Op: service_models.DiffOpDelete, Text: x[i]})
+			i++
+		default:
+			diff = append(diff, service_models.DiffLine{Op: service_models.DiffOpInsert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		diff = append(diff, service_models.DiffLine{Op: service_models.DiffOpDelete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		diff = append(diff, service_models.DiffLine{Op: service_models.DiffOpInsert, Text: y[j]})
+	}
+	return diff
+}
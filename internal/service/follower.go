@@ -11,19 +11,40 @@ type FollowerService interface {
 }
 
 type followerService struct {
-	followerRepo repository.FollowerRepository
+	followerRepo    repository.FollowerRepository
+	velocityService VelocityService
+	timelineService TimelineService
+	cacheRepository repository.CacheRepository
 }
 
 func (s *followerService) Follow(ctx context.Context, followerId, userId int64) error {
-	return s.followerRepo.Follow(ctx, followerId, userId)
+	if err := s.velocityService.Check(ctx, VelocityFollow, followerId); err != nil {
+		return err
+	}
+	if err := s.followerRepo.Follow(ctx, followerId, userId); err != nil {
+		return err
+	}
+	if err := s.timelineService.Follow(ctx, followerId, userId); err != nil {
+		return err
+	}
+	return s.cacheRepository.Delete(ctx, repository.FeedCacheKey(followerId))
 }
 
 func (s *followerService) Unfollow(ctx context.Context, followerId, userId int64) error {
-	return s.followerRepo.Unfollow(ctx, followerId, userId)
+	if err := s.followerRepo.Unfollow(ctx, followerId, userId); err != nil {
+		return err
+	}
+	if err := s.timelineService.Unfollow(ctx, followerId, userId); err != nil {
+		return err
+	}
+	return s.cacheRepository.Delete(ctx, repository.FeedCacheKey(followerId))
 }
 
-func NewFollowerService(followerRepo repository.FollowerRepository) FollowerService {
+func NewFollowerService(followerRepo repository.FollowerRepository, velocityService VelocityService, timelineService TimelineService, cacheRepository repository.CacheRepository) FollowerService {
 	return &followerService{
-		followerRepo: followerRepo,
+		followerRepo:    followerRepo,
+		velocityService: velocityService,
+		timelineService: timelineService,
+		cacheRepository: cacheRepository,
 	}
 }
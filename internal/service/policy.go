@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+)
+
+// PolicyService answers authorization questions by looking up the
+// permissions granted to a user's role.
+type PolicyService interface {
+	Can(ctx context.Context, user *service_models.User, permission string) (bool, error)
+	CanActOn(ctx context.Context, user *service_models.User, ownerId int64, permission string) (bool, error)
+	CanManage(ctx context.Context, user, target *service_models.User) (bool, error)
+}
+
+type policyService struct {
+	roleRepository repository.RoleRepository
+}
+
+func (p *policyService) Can(ctx context.Context, user *service_models.User, permission string) (bool, error) {
+	permissions, err := p.roleRepository.GetPermissions(ctx, user.Role.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, granted := range permissions {
+		if granted == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CanActOn allows owners to act on their own resources and falls back to the
+// given permission for everyone else.
+func (p *policyService) CanActOn(ctx context.Context, user *service_models.User, ownerId int64, permission string) (bool, error) {
+	if user.ID == ownerId {
+		return true, nil
+	}
+	return p.Can(ctx, user, permission)
+}
+
+// CanManage reports whether user may act against target's account, as by
+// suspending it. target's role must not rank above user's or grant any
+// permission user's role lacks, so moderators cannot act against admins.
+func (p *policyService) CanManage(ctx context.Context, user, target *service_models.User) (bool, error) {
+	if target.Role.Level > user.Role.Level {
+		return false, nil
+	}
+
+	granted, err := p.roleRepository.GetPermissions(ctx, user.Role.ID)
+	if err != nil {
+		return false, err
+	}
+	required, err := p.roleRepository.GetPermissions(ctx, target.Role.ID)
+	if err != nil {
+		return false, err
+	}
+
+	held := make(map[string]bool, len(granted))
+	for _, permission := range granted {
+		held[permission] = true
+	}
+	for _, permission := range required {
+		if !held[permission] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func NewPolicyService(roleRepository repository.RoleRepository) PolicyService {
+	return &policyService{
+		roleRepository: roleRepository,
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"database/sql"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
 	"github.com/saleh-ghazimoradi/Gophergram/utils"
@@ -12,41 +13,278 @@ type PostService interface {
 	Create(ctx context.Context, post *service_models.Post) error
 	GetById(ctx context.Context, id int64) (*service_models.Post, error)
 	GetUserFeed(ctx context.Context, id int64, fq service_models.PaginatedFeedQuery) ([]service_models.PostFeed, error)
-	Update(ctx context.Context, post *service_models.Post) error
+	Update(ctx context.Context, post *service_models.Post, editorId int64) error
 	Delete(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) error
+	GetRevisions(ctx context.Context, postId int64) ([]service_models.PostRevision, error)
+	DiffRevisions(ctx context.Context, post *service_models.Post, from, to int) (*service_models.PostRevisionDiff, error)
 }
 
 type postService struct {
-	postRepo repository.PostRepository
-	db       *sql.DB
+	postRepo        repository.PostRepository
+	followerRepo    repository.FollowerRepository
+	commentRepo     repository.CommentRepository
+	velocityService VelocityService
+	timelineService TimelineService
+	posts           *Cache[service_models.Post]
+	commentCounts   *Cache[int]
+	feeds           *Cache[[]feedEntry]
+	db              *sql.DB
 }
 
 func (p *postService) Create(ctx context.Context, post *service_models.Post) error {
-	return utils.WithTransaction(ctx, p.db, func(tx *sql.Tx) error {
+	if err := p.velocityService.Check(ctx, VelocityPost, post.UserID); err != nil {
+		return err
+	}
+	err := utils.WithTransaction(ctx, p.db, func(tx *sql.Tx) error {
 		userRepoWithTx := p.postRepo.WithTx(tx)
 		return userRepoWithTx.Create(ctx, post)
 	})
+	if err != nil {
+		return err
+	}
+	if err = p.timelineService.Publish(ctx, post); err != nil {
+		return err
+	}
+	return p.invalidateFeeds(ctx, post.UserID)
 }
 
 func (p *postService) GetById(ctx context.Context, id int64) (*service_models.Post, error) {
-	return p.postRepo.GetById(ctx, id)
+	post, err := p.posts.Fetch(ctx, repository.PostCacheKey(id), func(ctx context.Context) (service_models.Post, error) {
+		post, err := p.postRepo.GetById(ctx, id)
+		if err != nil {
+			return service_models.Post{}, err
+		}
+		return *post, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
 }
 
-func (p *postService) Update(ctx context.Context, post *service_models.Post) error {
-	return p.postRepo.Update(ctx, post)
+// Update saves the post and keeps the state it replaced as a revision.
+func (p *postService) Update(ctx context.Context, post *service_models.Post, editorId int64) error {
+	err := utils.WithTransaction(ctx, p.db, func(tx *sql.Tx) error {
+		postRepoWithTx := p.postRepo.WithTx(tx)
+
+		previous, err := postRepoWithTx.GetById(ctx, post.ID)
+		if err != nil {
+			return err
+		}
+
+		if err = postRepoWithTx.Update(ctx, post); err != nil {
+			return err
+		}
+
+		return postRepoWithTx.CreateRevision(ctx, &service_models.PostRevision{
+			PostID:   previous.ID,
+			Version:  previous.Version,
+			Title:    previous.Title,
+			Content:  previous.Content,
+			EditedBy: &editorId,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return p.invalidate(ctx, post.ID, post.UserID)
 }
 
 func (p *postService) Delete(ctx context.Context, id int64) error {
-	return p.postRepo.Delete(ctx, id)
+	post, err := p.postRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = p.postRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err = p.timelineService.Retract(ctx, id, post.UserID); err != nil {
+		return err
+	}
+	return p.invalidate(ctx, id, post.UserID)
+}
+
+func (p *postService) Restore(ctx context.Context, id int64) error {
+	if err := p.postRepo.Restore(ctx, id); err != nil {
+		return err
+	}
+	post, err := p.postRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = p.timelineService.Publish(ctx, post); err != nil {
+		return err
+	}
+	return p.invalidate(ctx, id, post.UserID)
+}
+
+// invalidate drops the cached post and every feed it appears in.
+func (p *postService) invalidate(ctx context.Context, postId, authorId int64) error {
+	if err := p.posts.Delete(ctx, repository.PostCacheKey(postId)); err != nil {
+		return err
+	}
+	return p.invalidateFeeds(ctx, authorId)
+}
+
+// invalidateFeeds drops the cached feeds of the author and their followers.
+func (p *postService) invalidateFeeds(ctx context.Context, authorId int64) error {
+	followerIds, err := p.followerRepo.GetFollowerIds(ctx, authorId)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(followerIds)+1)
+	keys = append(keys, repository.FeedCacheKey(authorId))
+	for _, id := range followerIds {
+		keys = append(keys, repository.FeedCacheKey(id))
+	}
+	return p.feeds.Delete(ctx, keys...)
+}
+
+func (p *postService) GetRevisions(ctx context.Context, postId int64) ([]service_models.PostRevision, error) {
+	return p.postRepo.GetRevisions(ctx, postId)
+}
+
+// DiffRevisions compares two versions of a post line by line. Either version
+// may be the current one.
+func (p *postService) DiffRevisions(ctx context.Context, post *service_models.Post, from, to int) (*service_models.PostRevisionDiff, error) {
+	older, err := p.revisionAt(ctx, post, from)
+	if err != nil {
+		return nil, err
+	}
+
+	newer, err := p.revisionAt(ctx, post, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service_models.PostRevisionDiff{
+		PostID:  post.ID,
+		From:    from,
+		To:      to,
+		Title:   diffLines(older.Title, newer.Title),
+		Content: diffLines(older.Content, newer.Content),
+	}, nil
 }
 
+func (p *postService) revisionAt(ctx context.Context, post *service_models.Post, version int) (*service_models.PostRevision, error) {
+	if version == post.Version {
+		return &service_models.PostRevision{
+			PostID:  post.ID,
+			Version: post.Version,
+			Title:   post.Title,
+			Content: post.Content,
+		}, nil
+	}
+	return p.postRepo.GetRevision(ctx, post.ID, version)
+}
+
+// feedEntry is a cached feed post. ViewerComments is not part of the API
+// response, so it is kept next to the post rather than inside it.
+type feedEntry struct {
+	Post           service_models.PostFeed `json:"post"`
+	ViewerComments int                     `json:"viewer_comments"`
+}
+
+// GetUserFeed serves the first page of the default feed from the cache. Comment
+// counts are cached per post and filled in on every read.
 func (p *postService) GetUserFeed(ctx context.Context, id int64, fq service_models.PaginatedFeedQuery) ([]service_models.PostFeed, error) {
+	load := func(ctx context.Context) ([]feedEntry, error) {
+		posts, err := p.loadFeed(ctx, id, fq)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]feedEntry, len(posts))
+		for i, post := range posts {
+			entries[i] = feedEntry{Post: post, ViewerComments: post.ViewerComments}
+		}
+		return entries, nil
+	}
+
+	var entries []feedEntry
+	var err error
+	if isDefaultFeedPage(fq) {
+		entries, err = p.feeds.Fetch(ctx, repository.FeedCacheKey(id), load)
+	} else {
+		entries, err = load(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	feed := make([]service_models.PostFeed, len(entries))
+	for i, entry := range entries {
+		count, err := p.commentCount(ctx, entry.Post.ID)
+		if err != nil {
+			return nil, err
+		}
+		feed[i] = entry.Post
+		feed[i].CommentCount = count + entry.ViewerComments
+	}
+	return feed, nil
+}
+
+// loadFeed reads the feed from the user's timeline where it can, and from the
+// feed query when it is filtered, sorted oldest first or paged past what
+// timelines keep.
+//
+// Timelines still hold posts that were hidden or whose author was
+// shadow-banned after they were written. Offsets count visible posts, as in
+// the feed query, so the timeline is read from its start and more of it is
+// read until the page is full or the timeline runs out.
+func (p *postService) loadFeed(ctx context.Context, id int64, fq service_models.PaginatedFeedQuery) ([]service_models.PostFeed, error) {
+	if fq.Sort == "desc" && fq.Search == "" && len(fq.Tags) == 0 && fq.Since.IsZero() && fq.Until.IsZero() {
+		want := fq.Offset + fq.Limit
+		for count := want; ; {
+			ids, ok, err := p.timelineService.Page(ctx, id, 0, count)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+
+			visible, err := p.postRepo.GetVisibleIds(ctx, id, ids)
+			if err != nil {
+				return nil, err
+			}
+			if len(visible) >= want || len(ids) < count {
+				page := visible[min(fq.Offset, len(visible)):min(want, len(visible))]
+				return p.postRepo.GetFeedByIds(ctx, id, page)
+			}
+			count += max(want-len(visible), fq.Limit)
+		}
+	}
 	return p.postRepo.GetUserFeed(ctx, id, fq)
 }
 
-func NewPostService(postRepo repository.PostRepository, db *sql.DB) PostService {
+func (p *postService) commentCount(ctx context.Context, postId int64) (int, error) {
+	return p.commentCounts.Fetch(ctx, repository.CommentCountCacheKey(postId), func(ctx context.Context) (int, error) {
+		return p.commentRepo.CountByPostId(ctx, postId)
+	})
+}
+
+func isDefaultFeedPage(fq service_models.PaginatedFeedQuery) bool {
+	return fq.Offset == 0 &&
+		fq.Limit == config.AppConfig.Pagination.Limit &&
+		fq.Sort == config.AppConfig.Pagination.Sort &&
+		fq.Search == "" &&
+		len(fq.Tags) == 0 &&
+		fq.Since.IsZero() &&
+		fq.Until.IsZero()
+}
+
+func NewPostService(postRepo repository.PostRepository, followerRepo repository.FollowerRepository, commentRepo repository.CommentRepository, velocityService VelocityService, timelineService TimelineService, cacheRepository repository.CacheRepository, db *sql.DB) PostService {
 	return &postService{
-		postRepo: postRepo,
-		db:       db,
+		postRepo:        postRepo,
+		followerRepo:    followerRepo,
+		commentRepo:     commentRepo,
+		velocityService: velocityService,
+		timelineService: timelineService,
+		posts:           NewCache[service_models.Post](cacheRepository, config.AppConfig.Cache.PostTTL),
+		commentCounts:   NewCache[int](cacheRepository, config.AppConfig.Cache.CommentCountTTL),
+		feeds:           NewCache[[]feedEntry](cacheRepository, config.AppConfig.Cache.FeedTTL),
+		db:              db,
 	}
 }
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"time"
+)
+
+// ReadYourWritesService remembers which users wrote within the last window,
+// so their reads can go to the primary until the replicas have caught up. It
+// keeps the marks in the cache so every instance sees them.
+type ReadYourWritesService interface {
+	MarkWrite(ctx context.Context, userId int64) error
+	RecentlyWrote(ctx context.Context, userId int64) bool
+}
+
+type readYourWritesService struct {
+	cacheRepository repository.CacheRepository
+	window          time.Duration
+}
+
+func (s *readYourWritesService) MarkWrite(ctx context.Context, userId int64) error {
+	if s.window <= 0 {
+		return nil
+	}
+	return s.cacheRepository.Set(ctx, repository.ReadYourWritesCacheKey(userId), []byte{1}, s.window)
+}
+
+func (s *readYourWritesService) RecentlyWrote(ctx context.Context, userId int64) bool {
+	if s.window <= 0 {
+		return false
+	}
+	data, err := s.cacheRepository.Get(ctx, repository.ReadYourWritesCacheKey(userId))
+	return err == nil && data != nil
+}
+
+// NewReadYourWritesService tracks nothing when window is zero, as when there
+// are no replicas to lag behind.
+func NewReadYourWritesService(cacheRepository repository.CacheRepository, window time.Duration) ReadYourWritesService {
+	return &readYourWritesService{
+		cacheRepository: cacheRepository,
+		window:          window,
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
+	"time"
+)
+
+type ReportService interface {
+	Create(ctx context.Context, report *service_models.Report) error
+	GetQueue(ctx context.Context, q service_models.ReportQueueQuery) ([]service_models.ReportGroup, error)
+	Resolve(ctx context.Context, targetType string, targetId int64, action string, moderator *service_models.User) error
+}
+
+type reportService struct {
+	reportRepo      repository.ReportRepository
+	postRepo        repository.PostRepository
+	commentRepo     repository.CommentRepository
+	userRepo        repository.UserRepository
+	suspensionRepo  repository.SuspensionRepository
+	policyService   PolicyService
+	cacheRepository repository.CacheRepository
+	db              *sql.DB
+}
+
+func (s *reportService) Create(ctx context.Context, report *service_models.Report) error {
+	if _, err := s.authorOf(ctx, report.TargetType, report.TargetID); err != nil {
+		return err
+	}
+	return s.reportRepo.Create(ctx, report)
+}
+
+func (s *reportService) GetQueue(ctx context.Context, q service_models.ReportQueueQuery) ([]service_models.ReportGroup, error) {
+	return s.reportRepo.GetQueue(ctx, q)
+}
+
+// Resolve applies a moderation action to reported content and closes every
+// open report filed against it. Suspending the author is held to the same
+// rules as the suspension endpoint.
+func (s *reportService) Resolve(ctx context.Context, targetType string, targetId int64, action string, moderator *service_models.User) error {
+	var authorId int64
+	if action == service_models.ReportActionSuspend {
+		var err error
+		if authorId, err = s.authorOf(ctx, targetType, targetId); err != nil {
+			return err
+		}
+		if err = s.checkCanSuspend(ctx, moderator, authorId); err != nil {
+			return err
+		}
+	}
+
+	stale, err := s.staleCacheKeys(ctx, targetType, targetId, action, authorId)
+	if err != nil {
+		return err
+	}
+
+	status := service_models.ReportStatusResolved
+	if action == service_models.ReportActionDismiss {
+		status = service_models.ReportStatusDismissed
+	}
+
+	err = utils.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.apply(ctx, tx, targetType, targetId, action, authorId, moderator.ID); err != nil {
+			return err
+		}
+		return s.reportRepo.WithTx(tx).Resolve(ctx, targetType, targetId, status, action, moderator.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.cacheRepository.Delete(ctx, stale...)
+}
+
+// staleCacheKeys lists the cached entries that resolving with action
+// outdates. Feeds are left to expire.
+func (s *reportService) staleCacheKeys(ctx context.Context, targetType string, targetId int64, action string, authorId int64) ([]string, error) {
+	switch {
+	case action == service_models.ReportActionSuspend:
+		return []string{repository.UserCacheKey(authorId)}, nil
+	case action != service_models.ReportActionHide && action != service_models.ReportActionDelete:
+		return nil, nil
+	case targetType == service_models.ReportTargetPost:
+		return []string{repository.PostCacheKey(targetId)}, nil
+	case targetType == service_models.ReportTargetComment:
+		comment, err := s.commentRepo.GetById(ctx, targetId)
+		if errors.Is(err, repository.ErrsNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return []string{repository.CommentCountCacheKey(comment.PostID)}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (s *reportService) apply(ctx context.Context, tx *sql.Tx, targetType string, targetId int64, action string, authorId, moderatorId int64) error {
+	var err error
+	switch action {
+	case service_models.ReportActionDismiss:
+		return nil
+	case service_models.ReportActionSuspend:
+		expiresAt := time.Now().Add(config.AppConfig.Moderation.ReportSuspension)
+		return s.suspensionRepo.WithTx(tx).Create(ctx, &service_models.Suspension{
+			UserID:    authorId,
+			Reason:    "suspended after reported " + targetType,
+			ExpiresAt: &expiresAt,
+			CreatedBy: &moderatorId,
+		})
+	case service_models.ReportActionHide:
+		switch targetType {
+		case service_models.ReportTargetPost:
+			err = s.postRepo.WithTx(tx).Hide(ctx, targetId)
+		case service_models.ReportTargetComment:
+			err = s.commentRepo.WithTx(tx).Hide(ctx, targetId)
+		default:
+			return repository.ErrInvalidAction
+		}
+	case service_models.ReportActionDelete:
+		switch targetType {
+		case service_models.ReportTargetPost:
+			err = s.postRepo.WithTx(tx).Delete(ctx, targetId)
+		case service_models.ReportTargetComment:
+			err = s.commentRepo.WithTx(tx).Delete(ctx, targetId)
+		default:
+			return repository.ErrInvalidAction
+		}
+	default:
+		return repository.ErrInvalidAction
+	}
+
+	// content that is already hidden or gone only needs its reports closed
+	if errors.Is(err, repository.ErrsNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *reportService) checkCanSuspend(ctx context.Context, moderator *service_models.User, authorId int64) error {
+	if moderator.ID == authorId {
+		return repository.ErrSuspendSelf
+	}
+
+	author, err := s.userRepo.GetById(ctx, authorId)
+	if err != nil {
+		return err
+	}
+	allowed, err := s.policyService.CanManage(ctx, moderator, author)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return repository.ErrOutranked
+	}
+	return nil
+}
+
+func (s *reportService) authorOf(ctx context.Context, targetType string, targetId int64) (int64, error) {
+	switch targetType {
+	case service_models.ReportTargetPost:
+		post, err := s.postRepo.GetById(ctx, targetId)
+		if err != nil {
+			return 0, err
+		}
+		return post.UserID, nil
+	case service_models.ReportTargetComment:
+		comment, err := s.commentRepo.GetById(ctx, targetId)
+		if err != nil {
+			return 0, err
+		}
+		return comment.UserID, nil
+	case service_models.ReportTargetUser:
+		user, err := s.userRepo.GetById(ctx, targetId)
+		if err != nil {
+			return 0, err
+		}
+		return user.ID, nil
+	default:
+		return 0, repository.ErrInvalidAction
+	}
+}
+
+func NewReportService(reportRepo repository.ReportRepository, postRepo repository.PostRepository, commentRepo repository.CommentRepository, userRepo repository.UserRepository, suspensionRepo repository.SuspensionRepository, policyService PolicyService, cacheRepository repository.CacheRepository, db *sql.DB) ReportService {
+	return &reportService{
+		reportRepo:      reportRepo,
+		postRepo:        postRepo,
+		commentRepo:     commentRepo,
+		userRepo:        userRepo,
+		suspensionRepo:  suspensionRepo,
+		policyService:   policyService,
+		cacheRepository: cacheRepository,
+		db:              db,
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"time"
+)
+
+// RetentionService permanently removes content that has stayed soft-deleted
+// longer than the retention period.
+type RetentionService interface {
+	Purge(ctx context.Context, period time.Duration) (posts int64, comments int64, err error)
+}
+
+type retentionService struct {
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+}
+
+func (r *retentionService) Purge(ctx context.Context, period time.Duration) (int64, int64, error) {
+	deletedBefore := time.Now().Add(-period)
+
+	posts, err := r.postRepo.Purge(ctx, deletedBefore)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	comments, err := r.commentRepo.Purge(ctx, deletedBefore)
+	if err != nil {
+		return posts, 0, err
+	}
+	return posts, comments, nil
+}
+
+func NewRetentionService(postRepo repository.PostRepository, commentRepo repository.CommentRepository) RetentionService {
+	return &retentionService{
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+	}
+}
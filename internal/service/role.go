@@ -2,24 +2,130 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"github.com/saleh-ghazimoradi/Gophergram/utils"
 )
 
+// DefaultRole is the role every user gets at sign-up and falls back to when
+// an assigned role is revoked.
+const DefaultRole = "user"
+
 type RoleService interface {
 	GetByName(ctx context.Context, name string) (*service_models.Role, error)
+	GetById(ctx context.Context, id int64) (*service_models.Role, error)
+	GetAll(ctx context.Context) ([]service_models.Role, error)
+	Create(ctx context.Context, role *service_models.Role) error
+	Update(ctx context.Context, role *service_models.Role) error
+	AssignToUser(ctx context.Context, userId int64, roleName string, changedBy *int64) (*service_models.RoleAssignment, error)
+	RevokeFromUser(ctx context.Context, userId int64, changedBy *int64) (*service_models.RoleAssignment, error)
 }
 
 type roleService struct {
-	roleRepository repository.RoleRepository
+	roleRepository  repository.RoleRepository
+	userRepository  repository.UserRepository
+	cacheRepository repository.CacheRepository
+	db              *sql.DB
 }
 
 func (s *roleService) GetByName(ctx context.Context, name string) (*service_models.Role, error) {
 	return s.roleRepository.GetByName(ctx, name)
 }
 
-func NewRoleService(roleRepository repository.RoleRepository) RoleService {
+func (s *roleService) GetById(ctx context.Context, id int64) (*service_models.Role, error) {
+	return s.roleRepository.GetById(ctx, id)
+}
+
+func (s *roleService) GetAll(ctx context.Context) ([]service_models.Role, error) {
+	return s.roleRepository.GetAll(ctx)
+}
+
+func (s *roleService) Create(ctx context.Context, role *service_models.Role) error {
+	return utils.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		roleRepoWithTx := s.roleRepository.WithTx(tx)
+		if err := roleRepoWithTx.Create(ctx, role); err != nil {
+			return err
+		}
+		role.Permissions = uniquePermissions(role.Permissions)
+		return roleRepoWithTx.SetPermissions(ctx, role.ID, role.Permissions)
+	})
+}
+
+// Update saves the description and level of a role. Its permissions are only
+// replaced when role.Permissions is not nil.
+func (s *roleService) Update(ctx context.Context, role *service_models.Role) error {
+	return utils.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		roleRepoWithTx := s.roleRepository.WithTx(tx)
+		if err := roleRepoWithTx.Update(ctx, role); err != nil {
+			return err
+		}
+		if role.Permissions == nil {
+			return nil
+		}
+		role.Permissions = uniquePermissions(role.Permissions)
+		return roleRepoWithTx.SetPermissions(ctx, role.ID, role.Permissions)
+	})
+}
+
+func (s *roleService) AssignToUser(ctx context.Context, userId int64, roleName string, changedBy *int64) (*service_models.RoleAssignment, error) {
+	role, err := s.roleRepository.GetByName(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Roles can be assigned before an account is activated, so the user is
+	// not looked up through GetById.
+	assignment := &service_models.RoleAssignment{
+		UserID:    userId,
+		NewRoleID: role.ID,
+		ChangedBy: changedBy,
+	}
+
+	err = utils.WithTransaction(ctx, s.db, func(tx *sql.Tx) error {
+		userRepoWithTx := s.userRepository.WithTx(tx)
+		oldRoleId, err := userRepoWithTx.GetRoleId(ctx, userId)
+		if err != nil {
+			return err
+		}
+		assignment.OldRoleID = &oldRoleId
+
+		if err = userRepoWithTx.SetRole(ctx, userId, role.ID); err != nil {
+			return err
+		}
+		return s.roleRepository.WithTx(tx).CreateAssignment(ctx, assignment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.cacheRepository.Delete(ctx, repository.UserCacheKey(userId)); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+func (s *roleService) RevokeFromUser(ctx context.Context, userId int64, changedBy *int64) (*service_models.RoleAssignment, error) {
+	return s.AssignToUser(ctx, userId, DefaultRole, changedBy)
+}
+
+func uniquePermissions(permissions []string) []string {
+	seen := make(map[string]bool, len(permissions))
+	unique := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+	return unique
+}
+
+func NewRoleService(roleRepository repository.RoleRepository, userRepository repository.UserRepository, cacheRepository repository.CacheRepository, db *sql.DB) RoleService {
 	return &roleService{
-		roleRepository: roleRepository,
+		roleRepository:  roleRepository,
+		userRepository:  userRepository,
+		cacheRepository: cacheRepository,
+		db:              db,
 	}
 }
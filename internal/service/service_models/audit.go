@@ -0,0 +1,96 @@
+package service_models
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type AuditLog struct {
+	ID         int64           `json:"id"`
+	ActorID    *int64          `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   *int64          `json:"target_id"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type AuditQuery struct {
+	Limit      int       `json:"limit" validate:"gte=1,lte=100"`
+	Offset     int       `json:"offset" validate:"gte=0"`
+	ActorID    int64     `json:"actor_id" validate:"gte=0"`
+	Action     string    `json:"action" validate:"max=100"`
+	TargetType string    `json:"target_type" validate:"max=50"`
+	TargetID   int64     `json:"target_id" validate:"gte=0"`
+	Since      time.Time `json:"since"`
+	Until      time.Time `json:"until"`
+}
+
+func (q AuditQuery) Parse(r *http.Request) (AuditQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	actorId := qs.Get("actor_id")
+	if actorId != "" {
+		id, err := strconv.ParseInt(actorId, 10, 64)
+		if err != nil {
+			return q, err
+		}
+		q.ActorID = id
+	}
+
+	action := qs.Get("action")
+	if action != "" {
+		q.Action = action
+	}
+
+	targetType := qs.Get("target_type")
+	if targetType != "" {
+		q.TargetType = targetType
+	}
+
+	targetId := qs.Get("target_id")
+	if targetId != "" {
+		id, err := strconv.ParseInt(targetId, 10, 64)
+		if err != nil {
+			return q, err
+		}
+		q.TargetID = id
+	}
+
+	since, err := parseTime(qs.Get("since"))
+	if err != nil {
+		return q, err
+	}
+	q.Since = since
+
+	until, err := parseTime(qs.Get("until"))
+	if err != nil {
+		return q, err
+	}
+	q.Until = until
+
+	return q, nil
+}
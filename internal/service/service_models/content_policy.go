@@ -0,0 +1,39 @@
+package service_models
+
+const (
+	ContentDecisionAllow  = "allow"
+	ContentDecisionFlag   = "flag"
+	ContentDecisionReject = "reject"
+)
+
+// Content is a post or comment as seen by the content policy. ID is zero
+// while the content has not been stored yet.
+type Content struct {
+	Type   string
+	ID     int64
+	UserID int64
+	Title  string
+	Body   string
+}
+
+type ContentVerdict struct {
+	Decision string `json:"decision"`
+	Rule     string `json:"rule"`
+	Reason   string `json:"reason"`
+}
+
+// ContentEvaluation is the outcome of running every content rule. The first
+// rejection stops evaluation; flags are collected so they can be reported
+// once the content is stored.
+type ContentEvaluation struct {
+	Rejection *ContentVerdict
+	Flags     []ContentVerdict
+}
+
+func (e *ContentEvaluation) Rejected() bool {
+	return e.Rejection != nil
+}
+
+type CreateCommentPayload struct {
+	Content string `json:"content" validate:"required,max=1000"`
+}
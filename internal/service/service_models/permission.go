@@ -0,0 +1,21 @@
+package service_models
+
+const (
+	PermissionPostsUpdateAny = "posts.update.any"
+	PermissionPostsDeleteAny = "posts.delete.any"
+	PermissionCommentsHide   = "comments.hide"
+	PermissionUsersDelete    = "users.delete"
+	PermissionRolesManage    = "roles.manage"
+	PermissionReportsReview  = "reports.review"
+	PermissionContentRestore = "content.restore"
+	PermissionAuditRead      = "audit.read"
+	PermissionUsersSuspend   = "users.suspend"
+	PermissionUsersShadowBan = "users.shadowban"
+	PermissionStatsRead      = "stats.read"
+)
+
+type Permission struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
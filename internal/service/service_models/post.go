@@ -11,6 +11,7 @@ type Post struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Version   int       `json:"version"`
+	Edited    bool      `json:"edited"`
 	Comments  []Comment `json:"comments"`
 	User      User      `json:"user"`
 }
@@ -29,4 +30,7 @@ type UpdatePostPayload struct {
 type PostFeed struct {
 	Post
 	CommentCount int `json:"comment_count"`
+	// ViewerComments counts the viewer's own comments that only they can
+	// see because they are shadow-banned. It is added to the shared count.
+	ViewerComments int `json:"-"`
 }
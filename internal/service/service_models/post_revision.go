@@ -0,0 +1,34 @@
+package service_models
+
+import "time"
+
+const (
+	DiffOpEqual  = "="
+	DiffOpInsert = "+"
+	DiffOpDelete = "-"
+)
+
+// PostRevision is the state of a post before an update replaced it. Version is
+// the version the post had at that point.
+type PostRevision struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	Version   int       `json:"version"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	EditedBy  *int64    `json:"edited_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type PostRevisionDiff struct {
+	PostID  int64      `json:"post_id"`
+	From    int        `json:"from"`
+	To      int        `json:"to"`
+	Title   []DiffLine `json:"title"`
+	Content []DiffLine `json:"content"`
+}
@@ -0,0 +1,22 @@
+package service_models
+
+import "time"
+
+// RateLimitPolicy limits a group of routes. Each client has a separate
+// allowance per policy Name.
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult is the outcome of one hit against a rate limit. Reset is
+// the time until the whole allowance is available again and RetryAfter the
+// time until the next hit would be admitted, which is zero when Allowed.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
@@ -0,0 +1,109 @@
+package service_models
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+
+	ReportStatusOpen      = "open"
+	ReportStatusDismissed = "dismissed"
+	ReportStatusResolved  = "resolved"
+
+	ReportActionDismiss = "dismiss"
+	ReportActionHide    = "hide"
+	ReportActionDelete  = "delete"
+	ReportActionSuspend = "suspend"
+)
+
+type Report struct {
+	ID         int64      `json:"id"`
+	ReporterID *int64     `json:"reporter_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   int64      `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	Resolution *string    `json:"resolution"`
+	ResolvedBy *int64     `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ReportGroup collects every report filed against the same piece of content.
+type ReportGroup struct {
+	TargetType      string    `json:"target_type"`
+	TargetID        int64     `json:"target_id"`
+	Status          string    `json:"status"`
+	ReportCount     int       `json:"report_count"`
+	Reasons         []string  `json:"reasons"`
+	FirstReportedAt time.Time `json:"first_reported_at"`
+	LastReportedAt  time.Time `json:"last_reported_at"`
+}
+
+type CreateReportPayload struct {
+	TargetType string `json:"target_type" validate:"required,oneof=post comment user"`
+	TargetID   int64  `json:"target_id" validate:"required,gte=1"`
+	Reason     string `json:"reason" validate:"required,max=500"`
+}
+
+type ResolveReportsPayload struct {
+	TargetType string `json:"target_type" validate:"required,oneof=post comment user"`
+	TargetID   int64  `json:"target_id" validate:"required,gte=1"`
+	Action     string `json:"action" validate:"required,oneof=dismiss hide delete suspend"`
+}
+
+type ReportQueueQuery struct {
+	Limit      int    `json:"limit" validate:"gte=1,lte=100"`
+	Offset     int    `json:"offset" validate:"gte=0"`
+	Status     string `json:"status" validate:"oneof=open dismissed resolved"`
+	TargetType string `json:"target_type" validate:"omitempty,oneof=post comment user"`
+	MinReports int    `json:"min_reports" validate:"gte=1"`
+}
+
+func (q ReportQueueQuery) Parse(r *http.Request) (ReportQueueQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	status := qs.Get("status")
+	if status != "" {
+		q.Status = status
+	}
+
+	targetType := qs.Get("target_type")
+	if targetType != "" {
+		q.TargetType = targetType
+	}
+
+	minReports := qs.Get("min_reports")
+	if minReports != "" {
+		m, err := strconv.Atoi(minReports)
+		if err != nil {
+			return q, err
+		}
+		q.MinReports = m
+	}
+
+	return q, nil
+}
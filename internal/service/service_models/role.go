@@ -1,8 +1,37 @@
 package service_models
 
+import "time"
+
 type Role struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Level       int64  `json:"level"`
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Level       int64    `json:"level"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+type CreateRolePayload struct {
+	Name        string   `json:"name" validate:"required,max=255"`
+	Description string   `json:"description" validate:"max=1000"`
+	Level       int64    `json:"level" validate:"gte=0"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRolePayload struct {
+	Description *string  `json:"description" validate:"omitempty,max=1000"`
+	Level       *int64   `json:"level" validate:"omitempty,gte=0"`
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRolePayload struct {
+	Role string `json:"role" validate:"required,max=255"`
+}
+
+type RoleAssignment struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	OldRoleID *int64    `json:"old_role_id"`
+	NewRoleID int64     `json:"new_role_id"`
+	ChangedBy *int64    `json:"changed_by"`
+	CreatedAt time.Time `json:"created_at"`
 }
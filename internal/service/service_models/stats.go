@@ -0,0 +1,40 @@
+package service_models
+
+import "time"
+
+// AdminStats is a snapshot of how the instance is doing. It is computed
+// periodically, so it may be a few minutes old; see GeneratedAt.
+type AdminStats struct {
+	GeneratedAt     time.Time    `json:"generated_at"`
+	ActiveUsers     ActiveUsers  `json:"active_users"`
+	Signups         []DailyCount `json:"signups"`
+	Activations     []DailyCount `json:"activations"`
+	Totals          StatsTotals  `json:"totals"`
+	ModerationQueue int64        `json:"moderation_queue"`
+	TopTags         []TagCount   `json:"top_tags"`
+}
+
+// ActiveUsers counts the distinct users seen through a session or a personal
+// access token in the last day, week and month.
+type ActiveUsers struct {
+	Day   int64 `json:"day"`
+	Week  int64 `json:"week"`
+	Month int64 `json:"month"`
+}
+
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+type StatsTotals struct {
+	Users    int64 `json:"users"`
+	Posts    int64 `json:"posts"`
+	Comments int64 `json:"comments"`
+	Follows  int64 `json:"follows"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
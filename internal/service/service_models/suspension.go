@@ -0,0 +1,29 @@
+package service_models
+
+import "time"
+
+// Suspension bars a user from signing in until it expires or is lifted. A
+// suspension without an expiry is a permanent ban.
+type Suspension struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	LiftedAt  *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy  *int64     `json:"lifted_by,omitempty"`
+}
+
+func (s *Suspension) IsActive() bool {
+	if s.LiftedAt != nil {
+		return false
+	}
+	return s.ExpiresAt == nil || s.ExpiresAt.After(time.Now())
+}
+
+type CreateSuspensionPayload struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+	// ExpiresInHours of zero bans the account permanently.
+	ExpiresInHours int `json:"expires_in_hours" validate:"gte=0,lte=87600"`
+}
@@ -0,0 +1,9 @@
+package service_models
+
+import "time"
+
+// TimelineEntry is a post in a home timeline, ordered by when it was created.
+type TimelineEntry struct {
+	PostID    int64
+	CreatedAt time.Time
+}
@@ -0,0 +1,62 @@
+package service_models
+
+import "time"
+
+const (
+	ScopePostsRead    = "posts:read"
+	ScopePostsWrite   = "posts:write"
+	ScopeFeedRead     = "feed:read"
+	ScopeUsersRead    = "users:read"
+	ScopeUsersWrite   = "users:write"
+	ScopeTokensRead   = "tokens:read"
+	ScopeSessionsRead = "sessions:read"
+
+	// The scopes below are never granted to a personal access token, so only
+	// JWT sessions can mint tokens, revoke sessions, change account
+	// credentials or reach the admin and moderation APIs.
+	ScopeTokensWrite   = "tokens:write"
+	ScopeSessionsWrite = "sessions:write"
+	ScopeAccountWrite  = "account:write"
+	ScopeAdmin         = "admin"
+)
+
+// Scopes lists every scope a personal access token can be granted.
+var Scopes = []string{
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopeFeedRead,
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeTokensRead,
+	ScopeSessionsRead,
+}
+
+type PersonalAccessToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Expiry     time.Time  `json:"expiry"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreatePersonalAccessTokenPayload struct {
+	Name      string   `json:"name" validate:"required,max=100"`
+	Scopes    []string `json:"scopes" validate:"required,min=1,dive,oneof=posts:read posts:write feed:read users:read users:write tokens:read sessions:read"`
+	ExpiresIn int      `json:"expires_in_days" validate:"required,gte=1,lte=365"`
+}
+
+type PersonalAccessTokenWithSecret struct {
+	*PersonalAccessToken
+	Token string `json:"token"`
+}
@@ -1,19 +1,32 @@
 package service_models
 
 import (
+	"errors"
 	"golang.org/x/crypto/bcrypt"
 	"time"
 )
 
 type User struct {
-	ID        int64     `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  Password  `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
-	IsActive  bool      `json:"is_active"`
-	RoleID    int64     `json:"role_id"`
-	Role      Role      `json:"role"`
+	ID         int64       `json:"id"`
+	Username   string      `json:"username"`
+	Email      string      `json:"email"`
+	Password   Password    `json:"-"`
+	CreatedAt  time.Time   `json:"created_at"`
+	IsActive   bool        `json:"is_active"`
+	RoleID     int64       `json:"role_id"`
+	Role       Role        `json:"role"`
+	Suspension *Suspension `json:"-"`
+}
+
+func (u *User) IsSuspended() bool {
+	return u.Suspension != nil && u.Suspension.IsActive()
+}
+
+// ShadowBan tells moderators whether a user's content is hidden from everyone
+// but the user.
+type ShadowBan struct {
+	UserID       int64 `json:"user_id"`
+	ShadowBanned bool  `json:"shadow_banned"`
 }
 
 type RegisterUserPayload struct {
@@ -32,6 +45,23 @@ type CreateUserTokenPayload struct {
 	Password string `json:"password" validate:"required,min=3,max=32"`
 }
 
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=32"`
+	NewPassword     string `json:"new_password" validate:"required,min=3,max=32"`
+}
+
+type ChangeEmailPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type EmailChange struct {
+	UserID    int64     `json:"user_id"`
+	OldEmail  string    `json:"old_email"`
+	NewEmail  string    `json:"new_email"`
+	Expiry    time.Time `json:"expiry"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Password struct {
 	Text *string
 	Hash []byte
@@ -46,3 +76,16 @@ func (p *Password) Set(text string) error {
 	p.Text = &text
 	return nil
 }
+
+func (p *Password) Matches(text string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(p.Hash, []byte(text))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"time"
+)
+
+// sessionTouchInterval limits how often last_seen_at is written for a
+// session, so authenticated requests don't all turn into writes.
+const sessionTouchInterval = time.Minute
+
+type SessionService interface {
+	Create(ctx context.Context, session *service_models.Session) error
+	Validate(ctx context.Context, id int64) (*service_models.Session, error)
+	GetByUserId(ctx context.Context, userId int64) ([]service_models.Session, error)
+	Revoke(ctx context.Context, id, userId int64) error
+	RevokeOthers(ctx context.Context, userId, currentId int64) error
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+}
+
+func (s *sessionService) Create(ctx context.Context, session *service_models.Session) error {
+	return s.sessionRepo.Create(ctx, session)
+}
+
+func (s *sessionService) Validate(ctx context.Context, id int64) (*service_models.Session, error) {
+	session, err := s.sessionRepo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsRevoked() {
+		return nil, repository.ErrSessionRevoked
+	}
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		if err = s.sessionRepo.Touch(ctx, session.ID); err != nil {
+			return nil, err
+		}
+		session.LastSeenAt = time.Now()
+	}
+	return session, nil
+}
+
+func (s *sessionService) GetByUserId(ctx context.Context, userId int64) ([]service_models.Session, error) {
+	return s.sessionRepo.GetByUserId(ctx, userId)
+}
+
+func (s *sessionService) Revoke(ctx context.Context, id, userId int64) error {
+	return s.sessionRepo.Revoke(ctx, id, userId)
+}
+
+func (s *sessionService) RevokeOthers(ctx context.Context, userId, currentId int64) error {
+	return s.sessionRepo.RevokeOthers(ctx, userId, currentId)
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+	}
+}
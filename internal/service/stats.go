@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"time"
+)
+
+// StatsService serves the admin statistics from Redis. Refresh recomputes them
+// and is meant to run periodically; Get only computes them itself when the
+// cache is empty.
+type StatsService interface {
+	Get(ctx context.Context) (*service_models.AdminStats, error)
+	Refresh(ctx context.Context) (*service_models.AdminStats, error)
+}
+
+type statsService struct {
+	statsRepo    repository.StatsRepository
+	statsCache   *Cache[service_models.AdminStats]
+	signupDays   int
+	topTagsLimit int
+}
+
+func (s *statsService) Get(ctx context.Context) (*service_models.AdminStats, error) {
+	stats, err := s.statsCache.Fetch(ctx, repository.StatsCacheKey, func(ctx context.Context) (service_models.AdminStats, error) {
+		stats, err := s.compute(ctx)
+		if err != nil {
+			return service_models.AdminStats{}, err
+		}
+		return *stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (s *statsService) Refresh(ctx context.Context) (*service_models.AdminStats, error) {
+	stats, err := s.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.statsCache.Set(ctx, repository.StatsCacheKey, *stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (s *statsService) compute(ctx context.Context) (*service_models.AdminStats, error) {
+	stats := &service_models.AdminStats{GeneratedAt: time.Now()}
+
+	active, err := s.statsRepo.GetActiveUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.ActiveUsers = *active
+
+	since := time.Now().AddDate(0, 0, -s.signupDays)
+	if stats.Signups, err = s.statsRepo.GetDailySignups(ctx, since); err != nil {
+		return nil, err
+	}
+	if stats.Activations, err = s.statsRepo.GetDailyActivations(ctx, since); err != nil {
+		return nil, err
+	}
+
+	totals, err := s.statsRepo.GetTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Totals = *totals
+
+	if stats.ModerationQueue, err = s.statsRepo.CountOpenReports(ctx); err != nil {
+		return nil, err
+	}
+	if stats.TopTags, err = s.statsRepo.GetTopTags(ctx, s.topTagsLimit); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// NewStatsService caches the statistics for twice the refresh interval so the
+// cache does not run empty between two refreshes.
+func NewStatsService(statsRepo repository.StatsRepository, cacheRepository repository.CacheRepository, interval time.Duration, signupDays, topTagsLimit int) StatsService {
+	return &statsService{
+		statsRepo:    statsRepo,
+		statsCache:   NewCache[service_models.AdminStats](cacheRepository, 2*interval),
+		signupDays:   signupDays,
+		topTagsLimit: topTagsLimit,
+	}
+}
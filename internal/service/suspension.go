@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"time"
+)
+
+type SuspensionService interface {
+	Suspend(ctx context.Context, userId int64, reason string, duration time.Duration, suspendedBy *int64) (*service_models.Suspension, error)
+	GetActive(ctx context.Context, userId int64) (*service_models.Suspension, error)
+	Lift(ctx context.Context, userId int64, liftedBy *int64) error
+}
+
+type suspensionService struct {
+	suspensionRepo  repository.SuspensionRepository
+	userRepo        repository.UserRepository
+	cacheRepository repository.CacheRepository
+}
+
+// Suspend bars a user from signing in for the given duration. A zero duration
+// bans the account until the suspension is lifted. suspendedBy is nil when the
+// suspension does not come from a user, such as from the CLI.
+func (s *suspensionService) Suspend(ctx context.Context, userId int64, reason string, duration time.Duration, suspendedBy *int64) (*service_models.Suspension, error) {
+	if _, err := s.userRepo.GetById(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	suspension := &service_models.Suspension{
+		UserID:    userId,
+		Reason:    reason,
+		CreatedBy: suspendedBy,
+	}
+	if duration > 0 {
+		expiresAt := time.Now().Add(duration)
+		suspension.ExpiresAt = &expiresAt
+	}
+
+	if err := s.suspensionRepo.Create(ctx, suspension); err != nil {
+		return nil, err
+	}
+
+	if err := s.cacheRepository.Delete(ctx, repository.UserCacheKey(userId)); err != nil {
+		return nil, err
+	}
+	return suspension, nil
+}
+
+func (s *suspensionService) GetActive(ctx context.Context, userId int64) (*service_models.Suspension, error) {
+	return s.suspensionRepo.GetActive(ctx, userId)
+}
+
+func (s *suspensionService) Lift(ctx context.Context, userId int64, liftedBy *int64) error {
+	if err := s.suspensionRepo.Lift(ctx, userId, liftedBy); err != nil {
+		return err
+	}
+	return s.cacheRepository.Delete(ctx, repository.UserCacheKey(userId))
+}
+
+func NewSuspensionService(suspensionRepo repository.SuspensionRepository, userRepo repository.UserRepository, cacheRepository repository.CacheRepository) SuspensionService {
+	return &suspensionService{
+		suspensionRepo:  suspensionRepo,
+		userRepo:        userRepo,
+		cacheRepository: cacheRepository,
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"slices"
+)
+
+// TimelineService keeps home timelines materialized in Redis. New posts are
+// copied into the timelines of the author's followers when they are written,
+// except for accounts with more followers than the fan-out limit: their posts
+// are merged in when a timeline is read.
+type TimelineService interface {
+	// Page returns the post ids of one page of a user's timeline, newest
+	// first. It reports false when the page has to come from the database,
+	// either because Redis is unavailable or because the page lies beyond
+	// what timelines keep.
+	Page(ctx context.Context, userId int64, offset, limit int) ([]int64, bool, error)
+	Publish(ctx context.Context, post *service_models.Post) error
+	Retract(ctx context.Context, postId, authorId int64) error
+	Follow(ctx context.Context, followerId, userId int64) error
+	Unfollow(ctx context.Context, followerId, userId int64) error
+	Rebuild(ctx context.Context, userId int64) error
+}
+
+type timelineService struct {
+	timelineRepo repository.TimelineRepository
+	postRepo     repository.PostRepository
+	followerRepo repository.FollowerRepository
+	maxLength    int
+	fanOutLimit  int
+}
+
+func (t *timelineService) Page(ctx context.Context, userId int64, offset, limit int) ([]int64, bool, error) {
+	count := offset + limit
+	if !t.timelineRepo.Available() || count > t.maxLength {
+		return nil, false, nil
+	}
+
+	entries, ok, err := t.timelineRepo.Range(ctx, userId, count)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		// Build missing timelines on first read; the backfill command only
+		// saves doing it here.
+		if entries, err = t.rebuild(ctx, userId); err != nil {
+			return nil, false, err
+		}
+	}
+
+	fanIn, err := t.postRepo.GetFanOutOnReadEntries(ctx, userId, t.fanOutLimit+1, count)
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged := mergeTimelineEntries(entries, fanIn)
+	if offset >= len(merged) {
+		return []int64{}, true, nil
+	}
+
+	page := merged[offset:min(count, len(merged))]
+	ids := make([]int64, len(page))
+	for i, entry := range page {
+		ids[i] = entry.PostID
+	}
+	return ids, true, nil
+}
+
+// Publish adds a new or restored post to the author's timeline and, unless
+// they have too many followers, to those of their followers.
+func (t *timelineService) Publish(ctx context.Context, post *service_models.Post) error {
+	if !t.timelineRepo.Available() {
+		return nil
+	}
+
+	userIds := []int64{post.UserID}
+
+	followers, err := t.followerRepo.CountFollowers(ctx, post.UserID)
+	if err != nil {
+		return err
+	}
+	if followers <= t.fanOutLimit {
+		followerIds, err := t.followerRepo.GetFollowerIds(ctx, post.UserID)
+		if err != nil {
+			return err
+		}
+		userIds = append(userIds, followerIds...)
+	}
+
+	entry := service_models.TimelineEntry{PostID: post.ID, CreatedAt: post.CreatedAt}
+	return t.timelineRepo.Add(ctx, userIds, []service_models.TimelineEntry{entry})
+}
+
+// Retract removes a post from every timeline it may be in. The author may
+// have crossed the fan-out limit since posting, so all followers are covered.
+func (t *timelineService) Retract(ctx context.Context, postId, authorId int64) error {
+	if !t.timelineRepo.Available() {
+		return nil
+	}
+
+	followerIds, err := t.followerRepo.GetFollowerIds(ctx, authorId)
+	if err != nil {
+		return err
+	}
+
+	userIds := append([]int64{authorId}, followerIds...)
+	return t.timelineRepo.Remove(ctx, userIds, []int64{postId})
+}
+
+// Follow copies the recent posts of the followed account into the follower's
+// timeline. Accounts past the fan-out limit are read on demand instead.
+func (t *timelineService) Follow(ctx context.Context, followerId, userId int64) error {
+	if !t.timelineRepo.Available() {
+		return nil
+	}
+
+	followers, err := t.followerRepo.CountFollowers(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if followers > t.fanOutLimit {
+		return nil
+	}
+
+	entries, err := t.postRepo.GetEntriesByUser(ctx, userId, t.maxLength)
+	if err != nil {
+		return err
+	}
+	return t.timelineRepo.Add(ctx, []int64{followerId}, entries)
+}
+
+func (t *timelineService) Unfollow(ctx context.Context, followerId, userId int64) error {
+	if !t.timelineRepo.Available() {
+		return nil
+	}
+
+	entries, err := t.postRepo.GetEntriesByUser(ctx, userId, t.maxLength)
+	if err != nil {
+		return err
+	}
+
+	postIds := make([]int64, len(entries))
+	for i, entry := range entries {
+		postIds[i] = entry.PostID
+	}
+	return t.timelineRepo.Remove(ctx, []int64{followerId}, postIds)
+}
+
+func (t *timelineService) Rebuild(ctx context.Context, userId int64) error {
+	_, err := t.rebuild(ctx, userId)
+	return err
+}
+
+func (t *timelineService) rebuild(ctx context.Context, userId int64) ([]service_models.TimelineEntry, error) {
+	entries, err := t.postRepo.GetTimelineEntries(ctx, userId, t.maxLength)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.timelineRepo.Replace(ctx, userId, entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// mergeTimelineEntries combines entries from both sources, newest first. A
+// post in both is kept once.
+func mergeTimelineEntries(a, b []service_models.TimelineEntry) []service_models.TimelineEntry {
+	seen := make(map[int64]bool, len(a)+len(b))
+	merged := make([]service_models.TimelineEntry, 0, len(a)+len(b))
+	for _, entry := range append(slices.Clip(a), b...) {
+		if seen[entry.PostID] {
+			continue
+		}
+		seen[entry.PostID] = true
+		merged = append(merged, entry)
+	}
+
+	slices.SortFunc(merged, func(x, y service_models.TimelineEntry) int {
+		if c := y.CreatedAt.Compare(x.CreatedAt); c != 0 {
+			return c
+		}
+		switch {
+		case x.PostID > y.PostID:
+			return -1
+		case x.PostID < y.PostID:
+			return 1
+		}
+		return 0
+	})
+	return merged
+}
+
+func NewTimelineService(timelineRepo repository.TimelineRepository, postRepo repository.PostRepository, followerRepo repository.FollowerRepository, maxLength, fanOutLimit int) TimelineService {
+	return &timelineService{
+		timelineRepo: timelineRepo,
+		postRepo:     postRepo,
+		followerRepo: followerRepo,
+		maxLength:    maxLength,
+		fanOutLimit:  fanOutLimit,
+	}
+}
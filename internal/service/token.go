@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+	"strings"
+	"time"
+)
+
+// PersonalAccessTokenPrefix marks bearer tokens that must be looked up in the
+// database instead of being parsed as a JWT.
+const PersonalAccessTokenPrefix = "ggp_"
+
+type PersonalAccessTokenService interface {
+	Create(ctx context.Context, token *service_models.PersonalAccessToken) (string, error)
+	Authenticate(ctx context.Context, plainToken string) (*service_models.PersonalAccessToken, error)
+	GetByUserId(ctx context.Context, userId int64) ([]service_models.PersonalAccessToken, error)
+	Delete(ctx context.Context, id, userId int64) error
+}
+
+type personalAccessTokenService struct {
+	tokenRepo repository.PersonalAccessTokenRepository
+}
+
+func (p *personalAccessTokenService) Create(ctx context.Context, token *service_models.PersonalAccessToken) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	plainToken := PersonalAccessTokenPrefix + hex.EncodeToString(secret)
+
+	if err := p.tokenRepo.Create(ctx, token, hashToken(plainToken)); err != nil {
+		return "", err
+	}
+	return plainToken, nil
+}
+
+func (p *personalAccessTokenService) Authenticate(ctx context.Context, plainToken string) (*service_models.PersonalAccessToken, error) {
+	token, err := p.tokenRepo.GetByHash(ctx, hashToken(plainToken))
+	if err != nil {
+		return nil, err
+	}
+	if err = p.tokenRepo.Touch(ctx, token.ID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	token.LastUsedAt = &now
+	return token, nil
+}
+
+func (p *personalAccessTokenService) GetByUserId(ctx context.Context, userId int64) ([]service_models.PersonalAccessToken, error) {
+	return p.tokenRepo.GetByUserId(ctx, userId)
+}
+
+func (p *personalAccessTokenService) Delete(ctx context.Context, id, userId int64) error {
+	return p.tokenRepo.Delete(ctx, id, userId)
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+func hashToken(plainToken string) string {
+	hash := sha256.Sum256([]byte(plainToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func NewPersonalAccessTokenService(tokenRepo repository.PersonalAccessTokenRepository) PersonalAccessTokenService {
+	return &personalAccessTokenService{
+		tokenRepo: tokenRepo,
+	}
+}
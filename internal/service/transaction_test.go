@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
+)
+
+// These tests need a Postgres database migrated with `make migrate-up`. Point
+// TEST_DATABASE_DSN at it to run them; they are skipped otherwise.
+
+var errInjected = errors.New("injected failure")
+
+func TestMain(m *testing.M) {
+	config.AppConfig = &config.Config{
+		Context: config.Context{ContextTimeout: 5 * time.Second},
+	}
+	os.Exit(m.Run())
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err = db.Ping(); err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	return db
+}
+
+// createTestUser inserts a user outside any transaction and removes it when
+// the test ends.
+func createTestUser(t *testing.T, db *sql.DB, user *service_models.User) {
+	t.Helper()
+
+	if err := repository.NewUserRepository(db, db).Create(context.Background(), user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	t.Cleanup(func() { deleteTestUser(t, db, user.Email) })
+}
+
+func deleteTestUser(t *testing.T, db *sql.DB, email string) {
+	t.Helper()
+
+	if _, err := db.Exec(`DELETE FROM users WHERE email = $1`, email); err != nil {
+		t.Errorf("removing user %s: %v", email, err)
+	}
+}
+
+func newTestUser(t *testing.T) *service_models.User {
+	t.Helper()
+
+	name := fmt.Sprintf("tx%d", time.Now().UnixNano())
+	user := &service_models.User{Username: name, Email: name + "@example.com"}
+	if err := user.Password.Set("password"); err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	return user
+}
+
+func countRows(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("counting rows: %v", err)
+	}
+	return n
+}
+
+// failingUserRepository runs the wrapped statement and then fails, as if the
+// transaction broke right after it.
+type failingUserRepository struct {
+	repository.UserRepository
+	failAfter string
+}
+
+func (f *failingUserRepository) WithTx(tx *sql.Tx) repository.UserRepository {
+	return &failingUserRepository{UserRepository: f.UserRepository.WithTx(tx), failAfter: f.failAfter}
+}
+
+func (f *failingUserRepository) CreateUserInvitation(ctx context.Context, token string, exp time.Duration, id int64) error {
+	if err := f.UserRepository.CreateUserInvitation(ctx, token, exp, id); err != nil || f.failAfter != "CreateUserInvitation" {
+		return err
+	}
+	return errInjected
+}
+
+func (f *failingUserRepository) UpdateUserInvitation(ctx context.Context, user *service_models.User) error {
+	if err := f.UserRepository.UpdateUserInvitation(ctx, user); err != nil || f.failAfter != "UpdateUserInvitation" {
+		return err
+	}
+	return errInjected
+}
+
+type failingPostRepository struct {
+	repository.PostRepository
+}
+
+func (f *failingPostRepository) WithTx(tx *sql.Tx) repository.PostRepository {
+	return &failingPostRepository{PostRepository: f.PostRepository.WithTx(tx)}
+}
+
+func (f *failingPostRepository) Create(ctx context.Context, post *service_models.Post) error {
+	if err := f.PostRepository.Create(ctx, post); err != nil {
+		return err
+	}
+	return errInjected
+}
+
+type allowVelocity struct{}
+
+func (allowVelocity) Check(context.Context, VelocityAction, int64) error { return nil }
+
+func TestCreateAndInviteRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	userRepo := &failingUserRepository{UserRepository: repository.NewUserRepository(db, db), failAfter: "CreateUserInvitation"}
+	users := NewUserService(userRepo, db)
+
+	user := newTestUser(t)
+	t.Cleanup(func() { deleteTestUser(t, db, user.Email) })
+
+	err := users.CreateAndInvite(context.Background(), user, "invite-token", time.Hour)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("CreateAndInvite error = %v, want the injected failure", err)
+	}
+
+	if n := countRows(t, db, `SELECT COUNT(*) FROM users WHERE email = $1`, user.Email); n != 0 {
+		t.Fatalf("%d users left behind by a failed invitation", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM user_invitations WHERE user_id = $1`, user.ID); n != 0 {
+		t.Fatalf("%d invitations left behind by a failed invitation", n)
+	}
+}
+
+func TestActivateRollsBack(t *testing.T) {
+	db := newTestDB(t)
+	plainRepo := repository.NewUserRepository(db, db)
+
+	user := newTestUser(t)
+	createTestUser(t, db, user)
+	// Invitations are stored by the hash of the token mailed out.
+	hash := sha256.Sum256([]byte("activate-token"))
+	if err := plainRepo.CreateUserInvitation(context.Background(), hex.EncodeToString(hash[:]), time.Hour, user.ID); err != nil {
+		t.Fatalf("creating invitation: %v", err)
+	}
+
+	userRepo := &failingUserRepository{UserRepository: plainRepo, failAfter: "UpdateUserInvitation"}
+	err := NewUserService(userRepo, db).Activate(context.Background(), "activate-token")
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("Activate error = %v, want the injected failure", err)
+	}
+
+	if n := countRows(t, db, `SELECT COUNT(*) FROM users WHERE id = $1 AND is_active`, user.ID); n != 0 {
+		t.Fatal("user was activated by a failed activation")
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM user_invitations WHERE user_id = $1`, user.ID); n != 1 {
+		t.Fatalf("%d invitations left, want the original one", n)
+	}
+}
+
+func TestCreatePostRollsBack(t *testing.T) {
+	db := newTestDB(t)
+
+	user := newTestUser(t)
+	createTestUser(t, db, user)
+
+	posts := &postService{
+		postRepo:        &failingPostRepository{PostRepository: repository.NewPostRepository(db, db)},
+		velocityService: allowVelocity{},
+		db:              db,
+	}
+
+	post := &service_models.Post{Title: "title", Content: "content", UserID: user.ID}
+	err := posts.Create(context.Background(), post)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("Create error = %v, want the injected failure", err)
+	}
+
+	if n := countRows(t, db, `SELECT COUNT(*) FROM posts WHERE user_id = $1`, user.ID); n != 0 {
+		t.Fatalf("%d posts left behind by a failed create", n)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
 	"github.com/saleh-ghazimoradi/Gophergram/internal/service/service_models"
 	"github.com/saleh-ghazimoradi/Gophergram/utils"
@@ -16,6 +17,15 @@ type UserService interface {
 	CreateAndInvite(ctx context.Context, user *service_models.User, token string, invitationExp time.Duration) error
 	Delete(ctx context.Context, id int64) error
 	Activate(ctx context.Context, token string) error
+	ActivateById(ctx context.Context, id int64) error
+	CreateActivated(ctx context.Context, user *service_models.User) error
+	PurgeInvitations(ctx context.Context) (int64, error)
+	GetShadowBan(ctx context.Context, id int64) (*service_models.ShadowBan, error)
+	SetShadowBan(ctx context.Context, id int64, banned bool) error
+	RequestEmailChange(ctx context.Context, user *service_models.User, newEmail, token, cancelToken string, exp time.Duration) (*service_models.EmailChange, error)
+	ConfirmEmailChange(ctx context.Context, token string) (*service_models.EmailChange, error)
+	CancelEmailChange(ctx context.Context, cancelToken string) (*service_models.EmailChange, error)
+	ChangePassword(ctx context.Context, id int64, currentPassword, newPassword string) error
 }
 
 type userService struct {
@@ -49,27 +59,74 @@ func (u *userService) CreateAndInvite(ctx context.Context, user *service_models.
 
 func (u *userService) Activate(ctx context.Context, token string) error {
 	return utils.WithTransaction(ctx, u.db, func(tx *sql.Tx) error {
-		user, err := u.userRepo.GetUserFromInvitation(ctx, token)
+		userRepoWithTx := u.userRepo.WithTx(tx)
+		user, err := userRepoWithTx.GetUserFromInvitation(ctx, token)
 		if err != nil {
 			return err
 		}
 		user.IsActive = true
-		if err = u.userRepo.UpdateUserInvitation(ctx, user); err != nil {
+		if err = userRepoWithTx.UpdateUserInvitation(ctx, user); err != nil {
+			return err
+		}
+		if err = userRepoWithTx.DeleteUserInvitation(ctx, user.ID); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// ActivateById activates an account without its invitation token, for
+// operators bootstrapping users from the CLI.
+func (u *userService) ActivateById(ctx context.Context, id int64) error {
+	return utils.WithTransaction(ctx, u.db, func(tx *sql.Tx) error {
+		userRepoWithTx := u.userRepo.WithTx(tx)
+		if err := userRepoWithTx.Activate(ctx, id); err != nil {
+			return err
+		}
+		return userRepoWithTx.DeleteUserInvitation(ctx, id)
+	})
+}
+
+// CreateActivated creates an account that is active from the start, for
+// operators bootstrapping users from the CLI.
+func (u *userService) CreateActivated(ctx context.Context, user *service_models.User) error {
+	return utils.WithTransaction(ctx, u.db, func(tx *sql.Tx) error {
+		userRepoWithTx := u.userRepo.WithTx(tx)
+		if err := userRepoWithTx.Create(ctx, user); err != nil {
 			return err
 		}
-		if err = u.userRepo.DeleteUserInvitation(ctx, user.ID); err != nil {
+		if err := userRepoWithTx.Activate(ctx, user.ID); err != nil {
 			return err
 		}
+		user.IsActive = true
 		return nil
 	})
 }
 
+// PurgeInvitations removes every invitation that has expired.
+func (u *userService) PurgeInvitations(ctx context.Context) (int64, error) {
+	return u.userRepo.PurgeInvitations(ctx, time.Now())
+}
+
+func (u *userService) GetShadowBan(ctx context.Context, id int64) (*service_models.ShadowBan, error) {
+	banned, err := u.userRepo.GetShadowBan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &service_models.ShadowBan{UserID: id, ShadowBanned: banned}, nil
+}
+
+func (u *userService) SetShadowBan(ctx context.Context, id int64, banned bool) error {
+	return u.userRepo.SetShadowBan(ctx, id, banned)
+}
+
 func (u *userService) Delete(ctx context.Context, id int64) error {
 	return utils.WithTransaction(ctx, u.db, func(tx *sql.Tx) error {
-		if err := u.userRepo.Delete(ctx, id); err != nil {
+		userRepoWithTx := u.userRepo.WithTx(tx)
+		if err := userRepoWithTx.Delete(ctx, id); err != nil {
 			return err
 		}
-		if err := u.userRepo.DeleteUserInvitation(ctx, id); err != nil {
+		if err := userRepoWithTx.DeleteUserInvitation(ctx, id); err != nil {
 			return err
 		}
 		return nil
@@ -80,6 +137,78 @@ func (u *userService) GetByEmail(ctx context.Context, email string) (*service_mo
 	return u.userRepo.GetByEmail(ctx, email)
 }
 
+func (u *userService) RequestEmailChange(ctx context.Context, user *service_models.User, newEmail, token, cancelToken string, exp time.Duration) (*service_models.EmailChange, error) {
+	_, err := u.userRepo.GetByEmail(ctx, newEmail)
+	switch {
+	case err == nil:
+		return nil, repository.ErrDuplicateEmail
+	case !errors.Is(err, repository.ErrsNotFound):
+		return nil, err
+	}
+
+	change := &service_models.EmailChange{
+		UserID:   user.ID,
+		OldEmail: user.Email,
+		NewEmail: newEmail,
+		Expiry:   time.Now().Add(exp),
+	}
+	if err = u.userRepo.CreateEmailChange(ctx, change, token, cancelToken); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (u *userService) ConfirmEmailChange(ctx context.Context, token string) (*service_models.EmailChange, error) {
+	var change *service_models.EmailChange
+	err := utils.WithTransaction(ctx, u.db, func(tx *sql.Tx) error {
+		userRepoWithTx := u.userRepo.WithTx(tx)
+		var err error
+		change, err = userRepoWithTx.GetEmailChange(ctx, token)
+		if err != nil {
+			return err
+		}
+		if err = userRepoWithTx.UpdateEmail(ctx, change.UserID, change.NewEmail); err != nil {
+			return err
+		}
+		return userRepoWithTx.DeleteEmailChange(ctx, change.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (u *userService) CancelEmailChange(ctx context.Context, cancelToken string) (*service_models.EmailChange, error) {
+	change, err := u.userRepo.GetEmailChangeByCancelToken(ctx, cancelToken)
+	if err != nil {
+		return nil, err
+	}
+	if err = u.userRepo.DeleteEmailChange(ctx, change.UserID); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (u *userService) ChangePassword(ctx context.Context, id int64, currentPassword, newPassword string) error {
+	user, err := u.userRepo.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	matches, err := user.Password.Matches(currentPassword)
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return repository.ErrInvalidPassword
+	}
+
+	if err = user.Password.Set(newPassword); err != nil {
+		return err
+	}
+	return u.userRepo.UpdatePassword(ctx, id, user.Password.Hash)
+}
+
 func NewUserService(userRepo repository.UserRepository, db *sql.DB) UserService {
 	return &userService{
 		userRepo: userRepo,
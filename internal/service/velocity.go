@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/internal/repository"
+	"time"
+)
+
+type VelocityAction string
+
+const (
+	VelocityFollow  VelocityAction = "follow"
+	VelocityPost    VelocityAction = "post"
+	VelocityComment VelocityAction = "comment"
+)
+
+// VelocityLimit allows Limit actions per Window, or NewAccountLimit while the
+// account is younger than the policy's NewAccountAge. A Limit of 0 means no
+// limit and a NewAccountLimit of 0 falls back to Limit.
+type VelocityLimit struct {
+	Window          time.Duration
+	Limit           int
+	NewAccountLimit int
+}
+
+type VelocityPolicy struct {
+	NewAccountAge time.Duration
+	Limits        map[VelocityAction]VelocityLimit
+}
+
+// VelocityError is returned when an account has used up its allowance for an
+// action. It matches repository.ErrRateLimitExceeded.
+type VelocityError struct {
+	Action     VelocityAction
+	RetryAfter time.Duration
+}
+
+func (e *VelocityError) Error() string {
+	return fmt.Sprintf("too many %s actions, retry after %s", e.Action, e.RetryAfter)
+}
+
+func (e *VelocityError) Unwrap() error {
+	return repository.ErrRateLimitExceeded
+}
+
+// VelocityService counts what each account does so a single account cannot
+// mass-follow or spam below the global request rate limit.
+type VelocityService interface {
+	Check(ctx context.Context, action VelocityAction, userId int64) error
+}
+
+type velocityService struct {
+	rateLimitRepository repository.RateLimitRepository
+	userRepo            repository.UserRepository
+	policy              VelocityPolicy
+}
+
+func (v *velocityService) Check(ctx context.Context, action VelocityAction, userId int64) error {
+	limit, err := v.limitFor(ctx, action, userId)
+	if err != nil || limit == 0 {
+		return err
+	}
+
+	window := v.policy.Limits[action].Window
+	key := fmt.Sprintf("velocity:%s:%d", action, userId)
+
+	result, err := v.rateLimitRepository.Allow(ctx, key, limit, window)
+	if err != nil {
+		return err
+	}
+
+	if !result.Allowed {
+		return &VelocityError{Action: action, RetryAfter: result.RetryAfter}
+	}
+	return nil
+}
+
+func (v *velocityService) limitFor(ctx context.Context, action VelocityAction, userId int64) (int, error) {
+	limit, ok := v.policy.Limits[action]
+	if !ok || limit.Window <= 0 {
+		return 0, nil
+	}
+
+	if v.policy.NewAccountAge <= 0 || limit.NewAccountLimit == 0 {
+		return limit.Limit, nil
+	}
+
+	user, err := v.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	if time.Since(user.CreatedAt) < v.policy.NewAccountAge {
+		return limit.NewAccountLimit, nil
+	}
+	return limit.Limit, nil
+}
+
+// NewVelocityService builds the service for policy. An empty policy allows
+// everything and never touches the repositories.
+func NewVelocityService(rateLimitRepository repository.RateLimitRepository, userRepo repository.UserRepository, policy VelocityPolicy) VelocityService {
+	return &velocityService{
+		rateLimitRepository: rateLimitRepository,
+		userRepo:            userRepo,
+		policy:              policy,
+	}
+}
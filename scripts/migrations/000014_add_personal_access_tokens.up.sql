@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  name varchar(100) NOT NULL,
+  token bytea UNIQUE NOT NULL,
+  scopes text [] NOT NULL DEFAULT '{}',
+  expiry timestamp(0) with time zone NOT NULL,
+  last_used_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  user_agent text NOT NULL DEFAULT '',
+  ip varchar(45) NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  last_seen_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  revoked_at timestamp(0) with time zone,
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS permissions (
+  id bigserial PRIMARY KEY,
+  name varchar(255) NOT NULL UNIQUE,
+  description text
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_id bigint NOT NULL,
+  permission_id bigint NOT NULL,
+
+  PRIMARY KEY (role_id, permission_id),
+  FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE,
+  FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+);
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('posts.update.any', 'Update posts written by other users'),
+  ('posts.delete.any', 'Delete posts written by other users'),
+  ('comments.hide', 'Hide comments written by other users'),
+  ('users.delete', 'Delete user accounts');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name IN ('posts.update.any', 'comments.hide')
+WHERE
+  roles.name IN ('moderator', 'admin');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name IN ('posts.delete.any', 'users.delete')
+WHERE
+  roles.name = 'admin';
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS role_assignments (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  old_role_id bigint,
+  new_role_id bigint NOT NULL,
+  changed_by bigint,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (old_role_id) REFERENCES roles (id) ON DELETE SET NULL,
+  FOREIGN KEY (new_role_id) REFERENCES roles (id) ON DELETE CASCADE,
+  FOREIGN KEY (changed_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_assignments_user_id ON role_assignments (user_id);
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('roles.manage', 'Create and edit roles and assign them to users');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name = 'roles.manage'
+WHERE
+  roles.name = 'admin';
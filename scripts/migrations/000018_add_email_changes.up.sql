@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+  token bytea PRIMARY KEY,
+  cancel_token bytea UNIQUE NOT NULL,
+  user_id bigint NOT NULL UNIQUE,
+  old_email citext NOT NULL,
+  new_email citext NOT NULL,
+  expiry timestamp(0) with time zone NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS reports (
+  id bigserial PRIMARY KEY,
+  reporter_id bigint,
+  target_type varchar(20) NOT NULL CHECK (target_type IN ('post', 'comment', 'user')),
+  target_id bigint NOT NULL,
+  reason text NOT NULL,
+  status varchar(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'resolved')),
+  resolution varchar(20),
+  resolved_by bigint,
+  resolved_at timestamp(0) with time zone,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+  FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE SET NULL,
+  FOREIGN KEY (resolved_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports (target_type, target_id);
+
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter_target ON reports (reporter_id, target_type, target_id) WHERE status = 'open';
+
+ALTER TABLE
+  posts
+ADD
+  COLUMN hidden_at timestamp(0) with time zone;
+
+ALTER TABLE
+  comments
+ADD
+  COLUMN hidden_at timestamp(0) with time zone;
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('reports.review', 'Review reported content and resolve reports');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name = 'reports.review'
+WHERE
+  roles.name IN ('moderator', 'admin');
@@ -0,0 +1,29 @@
+ALTER TABLE
+  posts
+ADD
+  COLUMN deleted_at timestamp(0) with time zone;
+
+ALTER TABLE
+  comments
+ADD
+  COLUMN deleted_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at) WHERE deleted_at IS NOT NULL;
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('content.restore', 'Restore deleted posts and comments');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name = 'content.restore'
+WHERE
+  roles.name = 'admin';
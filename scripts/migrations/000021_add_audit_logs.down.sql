@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE name = 'audit.read';
+
+DROP TABLE IF EXISTS audit_logs;
+
+DROP FUNCTION IF EXISTS audit_logs_append_only;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+  id bigserial PRIMARY KEY,
+  actor_id bigint,
+  action varchar(100) NOT NULL,
+  target_type varchar(50) NOT NULL,
+  target_id bigint,
+  before jsonb,
+  after jsonb,
+  ip varchar(45) NOT NULL DEFAULT '',
+  request_id varchar(64) NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs (target_type, target_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+
+-- The audit log is append-only
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('audit.read', 'Read the audit log');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name = 'audit.read'
+WHERE
+  roles.name = 'admin';
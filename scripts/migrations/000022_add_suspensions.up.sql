@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS suspensions (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  reason text NOT NULL,
+  expires_at timestamp(0) with time zone,
+  created_by bigint,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  lifted_at timestamp(0) with time zone,
+  lifted_by bigint,
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL,
+  FOREIGN KEY (lifted_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_suspensions_user_id ON suspensions (user_id) WHERE lifted_at IS NULL;
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('users.suspend', 'Suspend and ban user accounts');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name = 'users.suspend'
+WHERE
+  roles.name IN ('moderator', 'admin');
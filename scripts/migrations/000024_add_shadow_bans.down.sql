@@ -0,0 +1,6 @@
+DELETE FROM permissions WHERE name = 'users.shadowban';
+
+DROP INDEX IF EXISTS idx_users_shadow_banned;
+
+ALTER TABLE
+  users DROP COLUMN IF EXISTS shadow_banned;
@@ -0,0 +1,22 @@
+ALTER TABLE
+  users
+ADD
+  COLUMN shadow_banned boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_users_shadow_banned ON users (id) WHERE shadow_banned;
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('users.shadowban', 'Shadow-ban user accounts and see who is shadow-banned');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name = 'users.shadowban'
+WHERE
+  roles.name IN ('moderator', 'admin');
@@ -0,0 +1,12 @@
+DELETE FROM permissions WHERE name = 'stats.read';
+
+DROP INDEX IF EXISTS idx_sessions_last_seen_at;
+
+DROP INDEX IF EXISTS idx_users_activated_at;
+
+DROP INDEX IF EXISTS idx_users_created_at;
+
+ALTER TABLE
+  users
+DROP
+  COLUMN IF EXISTS activated_at;
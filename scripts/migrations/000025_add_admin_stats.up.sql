@@ -0,0 +1,33 @@
+ALTER TABLE
+  users
+ADD
+  COLUMN activated_at timestamp(0) with time zone;
+
+UPDATE
+  users
+SET
+  activated_at = created_at
+WHERE
+  is_active = true;
+
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_users_activated_at ON users (activated_at);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_last_seen_at ON sessions (last_seen_at);
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('stats.read', 'View instance statistics');
+
+INSERT INTO
+  role_permissions (role_id, permission_id)
+SELECT
+  roles.id,
+  permissions.id
+FROM
+  roles
+  JOIN permissions ON permissions.name = 'stats.read'
+WHERE
+  roles.name IN ('admin');
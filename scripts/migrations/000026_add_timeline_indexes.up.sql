@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id);
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at ON posts (user_id, created_at DESC);
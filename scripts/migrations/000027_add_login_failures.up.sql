@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS login_failures (
+  user_id bigint PRIMARY KEY,
+  attempts integer NOT NULL DEFAULT 1,
+  first_failed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  last_failed_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  last_ip varchar(45) NOT NULL DEFAULT '',
+
+  FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
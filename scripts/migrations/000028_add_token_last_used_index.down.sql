@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_personal_access_tokens_last_used_at;
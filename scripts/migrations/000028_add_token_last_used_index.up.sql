@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_last_used_at ON personal_access_tokens (last_used_at);
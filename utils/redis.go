@@ -1,16 +1,111 @@
 package utils
 
 import (
+	"context"
+	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
 	"github.com/saleh-ghazimoradi/Gophergram/logger"
+	"sync"
+	"sync/atomic"
 )
 
-func RedisConnection(addr, pw string, db int) (*redis.Client, error) {
+// RedisClient is a Redis client that remembers whether the server answered
+// lately. Callers check Healthy and fall back to in-process behaviour while
+// it is down; Check brings it back once the server answers again. A nil
+// RedisClient stands for Redis being disabled and is never healthy.
+type RedisClient struct {
+	*redis.Client
+	healthy atomic.Bool
+
+	mu        sync.Mutex
+	onRecover []func(ctx context.Context) error
+}
+
+// Healthy reports whether Redis should be used.
+func (c *RedisClient) Healthy() bool {
+	return c != nil && c.healthy.Load()
+}
+
+// OnRecover registers fn to run when Redis answers again after it was
+// marked down. Callers are only let back to Redis once every fn succeeded,
+// so fn can clear what went stale while writes were being skipped.
+func (c *RedisClient) OnRecover(fn func(ctx context.Context) error) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onRecover = append(c.onRecover, fn)
+}
+
+// Check pings Redis and updates the health state.
+func (c *RedisClient) Check(ctx context.Context) bool {
+	if c == nil {
+		return false
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, config.AppConfig.Context.ContextTimeout)
+	defer cancel()
+
+	if err := c.Ping(pingCtx).Err(); err != nil {
+		c.MarkDown(err)
+		return false
+	}
+
+	if c.healthy.Load() {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, fn := range c.onRecover {
+		if err := fn(ctx); err != nil {
+			logger.Logger.Warn("redis answered but could not be recovered, still running degraded", "err", err.Error())
+			return false
+		}
+	}
+
+	c.healthy.Store(true)
+	logger.Logger.Info("redis is available again")
+	return true
+}
+
+// MarkDown switches to degraded mode after a failed Redis call. The next
+// successful Check switches back.
+func (c *RedisClient) MarkDown(err error) {
+	if c != nil && c.healthy.Swap(false) {
+		logger.Logger.Warn("redis is unavailable, running degraded", "err", err.Error())
+	}
+}
+
+func (c *RedisClient) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.Client.Close()
+}
+
+// RedisConnection opens a client and pings it. The client is returned even
+// when the ping fails, marked unhealthy, so the caller can run degraded until
+// Redis is reachable.
+func RedisConnection(addr, pw string, db int) (*RedisClient, error) {
 	logger.Logger.Info("Connecting to Redis...")
-	return redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: pw,
-		DB:       db,
-	}), nil
+	client := &RedisClient{
+		Client: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: pw,
+			DB:       db,
+		}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.AppConfig.Context.ContextTimeout)
+	defer cancel()
 
+	if err := client.Ping(ctx).Err(); err != nil {
+		return client, fmt.Errorf("error pinging Redis: %w", err)
+	}
+	client.healthy.Store(true)
+	return client, nil
 }
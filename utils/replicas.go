@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/saleh-ghazimoradi/Gophergram/config"
+	"github.com/saleh-ghazimoradi/Gophergram/logger"
+	"sync/atomic"
+)
+
+// Querier runs statements against a database. *sql.DB and ReadPool both
+// implement it.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so reads made with it go to the primary, for callers
+// that must see their own recent writes.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// ReadPool spreads reads round-robin over the healthy replicas. It falls back
+// to the primary when none is healthy or when ctx was marked WithPrimary.
+// Statements that write always go to the primary.
+type ReadPool struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+func (p *ReadPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.primary.ExecContext(ctx, query, args...)
+}
+
+func (p *ReadPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.pick(ctx).QueryContext(ctx, query, args...)
+}
+
+func (p *ReadPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return p.pick(ctx).QueryRowContext(ctx, query, args...)
+}
+
+func (p *ReadPool) pick(ctx context.Context) *sql.DB {
+	if len(p.replicas) == 0 || usesPrimary(ctx) {
+		return p.primary
+	}
+
+	start := p.next.Add(1)
+	for i := range p.replicas {
+		r := p.replicas[(start+uint64(i))%uint64(len(p.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return p.primary
+}
+
+// Check pings every replica and updates which ones receive reads.
+func (p *ReadPool) Check(ctx context.Context) {
+	for i, r := range p.replicas {
+		err := r.ping(ctx)
+		switch {
+		case err != nil && r.healthy.Swap(false):
+			logger.Logger.Warn("read replica is unavailable, reading elsewhere", "replica", i, "err", err.Error())
+		case err == nil && !r.healthy.Swap(true):
+			logger.Logger.Info("read replica is available again", "replica", i)
+		}
+	}
+}
+
+func (r *replica) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.DBConfig.Timeout)
+	defer cancel()
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the replica pools. The primary belongs to the caller.
+func (p *ReadPool) Close() error {
+	for _, r := range p.replicas {
+		if err := r.db.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewReadPool opens a pool for each replica DSN with the primary's pool
+// settings. Replicas that cannot be reached start out unhealthy and are
+// picked up by a later Check.
+func NewReadPool(primary *sql.DB, dsns []string) (*ReadPool, error) {
+	pool := &ReadPool{primary: primary}
+	for i, dsn := range dsns {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			_ = pool.Close()
+			return nil, fmt.Errorf("error opening read replica %d: %w", i, err)
+		}
+		db.SetMaxOpenConns(config.AppConfig.DBConfig.MaxOpenConns)
+		db.SetMaxIdleConns(config.AppConfig.DBConfig.MaxIdleConns)
+		db.SetConnMaxLifetime(config.AppConfig.DBConfig.MaxIdleTime)
+
+		r := &replica{db: db}
+		if err = r.ping(context.Background()); err != nil {
+			logger.Logger.Warn("read replica is unavailable, reading elsewhere", "replica", i, "err", err.Error())
+		} else {
+			r.healthy.Store(true)
+		}
+		pool.replicas = append(pool.replicas, r)
+	}
+
+	if len(pool.replicas) > 0 {
+		logger.Logger.Info("reading from replicas", "count", len(pool.replicas))
+	}
+	return pool, nil
+}
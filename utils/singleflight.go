@@ -0,0 +1,44 @@
+package utils
+
+import "sync"
+
+// SingleFlight runs one call per key at a time. Callers that arrive while a
+// call for their key is in flight wait for it and share its result, which
+// keeps concurrent cache misses from all hitting the database.
+type SingleFlight[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+type flightCall[T any] struct {
+	wg    sync.WaitGroup
+	value T
+	err   error
+}
+
+func (g *SingleFlight[T]) Do(key string, fn func() (T, error)) (T, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+
+	call := &flightCall[T]{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.value, call.err = fn()
+	return call.value, call.err
+}
@@ -7,7 +7,7 @@ import (
 
 type TxFunc func(tx *sql.Tx) error
 
-func WithTransaction(ctx context.Context, db *sql.DB, fn TxFunc) error {
+func WithTransaction(ctx context.Context, db *sql.DB, fn TxFunc) (err error) {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err